@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+)
+
+type emailChangePayload struct {
+	Email    string
+	Password string
+}
+
+// AccountEmailChange starts a change of e-mail address once the user has confirmed it is them. The
+// current address stays in use, and sessions stay signed in, until the new address is verified.
+func (s *Server) AccountEmailChange(w http.ResponseWriter, r *http.Request) {
+	var payload emailChangePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.reauthenticate(w, r, payload.Password) {
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	validationErrors := validateEmail(s.Database, payload.Email)
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	user.PendingEmail = payload.Email
+
+	err = s.sendVerificationEmail(user, user.PendingEmail)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body := fmt.Sprintf("A request was made to change the e-mail address on your MOT.ninja account to %s. If this wasn't you, please reset your password.\n", user.PendingEmail)
+	err = s.Mailer.Send(user.Email, "E-mail address change requested", body)
+	if err != nil {
+		log.Println(err)
+	}
+
+	renderJSON(w, user, http.StatusOK)
+}
+
+// AccountShow returns the current user
+func (s *Server) AccountShow(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, getUserFromContext(r), http.StatusOK)
+}
+
+type accountPlanResponse struct {
+	*plans.Plan
+	VehicleCount int64
+}
+
+// AccountPlan returns what the current user's plan entitles them to, and how many vehicles they
+// have used
+func (s *Server) AccountPlan(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	response := accountPlanResponse{
+		Plan:         plans.Get(user.Plan),
+		VehicleCount: models.UserVehicleCount(s.Database, user.ID),
+	}
+
+	renderJSON(w, response, http.StatusOK)
+}
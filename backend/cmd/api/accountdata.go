@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// accountDeletionGracePeriod is how long a user has to change their mind before their account is
+// deleted by the background task
+const accountDeletionGracePeriod = 24 * time.Hour
+
+type accountExport struct {
+	ExportedAt    time.Time
+	Account       *models.User
+	Vehicles      []*vehicleExport
+	SharedWithMe  []*models.VehicleShare
+	Organisations []*models.Membership
+	Sessions      []*models.Session
+	APITokens     []*models.APIToken
+	AuditLog      []*models.AuditEntry
+}
+
+type vehicleExport struct {
+	*models.Vehicle
+	Events     []*models.VehicleEvent
+	SharedWith []*models.VehicleShare
+	ShareLinks []*models.ShareLink
+}
+
+// AccountExport downloads everything stored about the current user as a single JSON document:
+// their account and notification settings, personal vehicles with their full MOT history and
+// events, shares, organisation memberships, sessions, API tokens and audit log.
+func (s *Server) AccountExport(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	export, err := s.buildAccountExport(user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, user.ID, models.AuditAccountExported, "user", user.ID.Hex(), "")
+
+	filename := fmt.Sprintf("mot-ninja-export-%s.json", export.ExportedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	renderJSON(w, export, http.StatusOK)
+}
+
+func (s *Server) buildAccountExport(user *models.User) (*accountExport, error) {
+	export := accountExport{ExportedAt: time.Now(), Account: user}
+
+	vehicles, err := models.GetUserVehicles(s.Database, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vehicle := range vehicles {
+		if vehicle.Access != models.VehicleAccessOwner {
+			continue
+		}
+
+		vehicleExport := vehicleExport{Vehicle: vehicle}
+
+		vehicleExport.Events, err = models.GetVehicleEvents(s.Database, vehicle.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		vehicleExport.SharedWith, err = models.GetVehicleShares(s.Database, vehicle.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		vehicleExport.ShareLinks, err = models.GetVehicleShareLinks(s.Database, vehicle.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Vehicles = append(export.Vehicles, &vehicleExport)
+	}
+
+	export.SharedWithMe, err = models.GetUserVehicleShares(s.Database, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	export.Organisations, err = models.GetUserMemberships(s.Database, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	export.Sessions, err = models.GetUserSessions(s.Database, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	export.APITokens, err = models.GetUserAPITokens(s.Database, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A limit of zero fetches every entry
+	export.AuditLog, err = models.GetUserAuditEntries(s.Database, user.ID, primitive.NilObjectID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+type accountDeletionPayload struct {
+	Password string
+}
+
+// AccountDeletionSchedule schedules the current user's account for deletion once the grace period
+// has passed. Users confirm it with their password, or if they don't have one by having signed in
+// within the last few minutes. Users who are the only owner of an organisation with other members
+// must hand it over first.
+func (s *Server) AccountDeletionSchedule(w http.ResponseWriter, r *http.Request) {
+	var payload accountDeletionPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if !s.reauthenticate(w, r, payload.Password) {
+		return
+	}
+
+	validationErrors := s.validateAccountDeletion(user)
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	user.DeletionScheduledAt = time.Now().Add(accountDeletionGracePeriod)
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, user.ID, models.AuditAccountDeletionScheduled, "user", user.ID.Hex(), "")
+
+	body := fmt.Sprintf("Your MOT.ninja account will be deleted on %s, along with your vehicles. You can cancel this from your account page until then. If this wasn't you, please reset your password.\n", user.DeletionScheduledAt.Format(time.RFC1123))
+	err = s.Mailer.Send(user.Email, "Your account is scheduled for deletion", body)
+	if err != nil {
+		log.Println(err)
+	}
+
+	renderJSON(w, user, http.StatusOK)
+}
+
+// AccountDeletionCancel cancels a scheduled deletion of the current user's account
+func (s *Server) AccountDeletionCancel(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	if user.DeletionScheduledAt.IsZero() {
+		renderError(w, "Account is not scheduled for deletion", http.StatusUnprocessableEntity)
+		return
+	}
+
+	user.DeletionScheduledAt = time.Time{}
+
+	err := models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, user.ID, models.AuditAccountDeletionCancelled, "user", user.ID.Hex(), "")
+
+	renderJSON(w, user, http.StatusOK)
+}
+
+func (s *Server) validateAccountDeletion(user *models.User) []string {
+	var errors []string
+
+	if !user.DeletionScheduledAt.IsZero() {
+		errors = append(errors, "Account is already scheduled for deletion")
+	}
+
+	memberships, err := models.GetUserMemberships(s.Database, user.ID)
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	for _, membership := range memberships {
+		if membership.Role != models.RoleOwner || models.OrganisationOwnerCount(s.Database, membership.OrganisationID) > 1 {
+			continue
+		}
+
+		members, err := models.GetOrganisationMemberships(s.Database, membership.OrganisationID)
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+
+		if len(members) > 1 {
+			organisation, err := models.GetOrganisation(s.Database, membership.OrganisationID)
+			if err != nil {
+				errors = append(errors, err.Error())
+				continue
+			}
+
+			errors = append(errors, fmt.Sprintf("You must make another member an owner of %s first", organisation.Name))
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
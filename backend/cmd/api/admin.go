@@ -0,0 +1,330 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	adminPageSize     = 50
+	adminAuditEntries = 100
+)
+
+// AdminMiddleware refuses requests from users who aren't administrators. It must run after
+// AuthJwtTokenMiddleware.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		if !user.Admin {
+			renderError(w, "Administrator access is required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type adminUserListResponse struct {
+	Users []*models.User
+	Total int64
+	Page  int64
+}
+
+// AdminUserList lists users a page at a time, optionally searching by e-mail address with ?q=
+func (s *Server) AdminUserList(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	users, total, err := models.SearchUsers(s.Database, r.URL.Query().Get("q"), (page-1)*adminPageSize, adminPageSize)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if users == nil {
+		users = []*models.User{}
+	}
+
+	renderJSON(w, adminUserListResponse{Users: users, Total: total, Page: page}, http.StatusOK)
+}
+
+type adminUserResponse struct {
+	*models.User
+	VehicleCount int64
+}
+
+// AdminUserShow returns a single user
+func (s *Server) AdminUserShow(w http.ResponseWriter, r *http.Request) {
+	user, err := s.getAdminTargetUser(r)
+	if err != nil {
+		renderError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	renderJSON(w, adminUserResponse{User: user, VehicleCount: models.UserVehicleCount(s.Database, user.ID)}, http.StatusOK)
+}
+
+type adminPlanPayload struct {
+	Plan string
+}
+
+func (app *adminPlanPayload) Validate() []string {
+	var errors []string
+
+	_, err := plans.Lookup(app.Plan)
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+// AdminUserPlan moves a user onto another plan, which changes their vehicle limit, reminder
+// channels, API access and refresh frequency
+func (s *Server) AdminUserPlan(w http.ResponseWriter, r *http.Request) {
+	var payload adminPlanPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	user, err := s.getAdminTargetUser(r)
+	if err != nil {
+		renderError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	previousPlan := plans.Get(user.Plan).Name
+	user.Plan = payload.Plan
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, getUserFromContext(r).ID, models.AuditAdminPlanChanged, "user", user.ID.Hex(), fmt.Sprintf("%s to %s", previousPlan, user.Plan))
+
+	renderJSON(w, user, http.StatusOK)
+}
+
+// AdminOrganisationPlan moves an organisation onto another plan, which changes its vehicle limit
+// and how often its vehicles are refreshed
+func (s *Server) AdminOrganisationPlan(w http.ResponseWriter, r *http.Request) {
+	var payload adminPlanPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	organisationID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, errOrganisationNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	organisation, err := models.GetOrganisation(s.Database, organisationID)
+	if err != nil {
+		renderError(w, errOrganisationNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	previousPlan := plans.Get(organisation.Plan).Name
+	organisation.Plan = payload.Plan
+
+	err = models.UpdateOrganisation(s.Database, organisation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, getUserFromContext(r).ID, models.AuditAdminPlanChanged, "organisation", organisation.ID.Hex(), fmt.Sprintf("%s to %s", previousPlan, organisation.Plan))
+
+	renderJSON(w, organisation, http.StatusOK)
+}
+
+// AdminUserDisable stops a user from signing in and ends all of their sessions
+func (s *Server) AdminUserDisable(w http.ResponseWriter, r *http.Request) {
+	user, err := s.getAdminTargetUser(r)
+	if err != nil {
+		renderError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	if user.ID == admin.ID {
+		renderError(w, "You cannot disable your own account", http.StatusUnprocessableEntity)
+		return
+	}
+
+	user.Disabled = true
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = models.RevokeUserSessions(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, admin.ID, models.AuditAdminUserDisabled, "user", user.ID.Hex(), "")
+
+	renderJSON(w, user, http.StatusOK)
+}
+
+// AdminUserEnable allows a disabled user to sign in again
+func (s *Server) AdminUserEnable(w http.ResponseWriter, r *http.Request) {
+	user, err := s.getAdminTargetUser(r)
+	if err != nil {
+		renderError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	user.Disabled = false
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, getUserFromContext(r).ID, models.AuditAdminUserEnabled, "user", user.ID.Hex(), "")
+
+	renderJSON(w, user, http.StatusOK)
+}
+
+// AdminUserUnlock lifts a login lockout on a user's account
+func (s *Server) AdminUserUnlock(w http.ResponseWriter, r *http.Request) {
+	user, err := s.getAdminTargetUser(r)
+	if err != nil {
+		renderError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	err = UnlockAccount(s.Database, user.Email)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, getUserFromContext(r).ID, models.AuditAdminUserUnlocked, "user", user.ID.Hex(), "")
+
+	renderOkay(w, http.StatusOK)
+}
+
+// AdminVehicleRefresh queues a vehicle to be fetched again on the background task's next run
+func (s *Server) AdminVehicleRefresh(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	vehicleID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, "Vehicle not found", http.StatusNotFound)
+		return
+	}
+
+	err = models.ScheduleVehicleRefresh(s.Database, vehicleID)
+	if err != nil {
+		renderError(w, "Vehicle not found", http.StatusNotFound)
+		return
+	}
+
+	s.audit(r, getUserFromContext(r).ID, models.AuditAdminVehicleRefreshed, "vehicle", vehicleID.Hex(), "")
+
+	renderOkay(w, http.StatusOK)
+}
+
+// AdminJobList returns the status of each background job's most recent run
+func (s *Server) AdminJobList(w http.ResponseWriter, r *http.Request) {
+	runs, err := models.GetJobRuns(s.Database)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if runs == nil {
+		runs = []*models.JobRun{}
+	}
+
+	renderJSON(w, runs, http.StatusOK)
+}
+
+// AdminAuditList pages through the whole audit log, newest first. Pass the ID of the last entry
+// seen as ?before= to fetch the next page.
+func (s *Server) AdminAuditList(w http.ResponseWriter, r *http.Request) {
+	before, err := auditCursor(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := models.GetAuditEntries(s.Database, before, adminAuditEntries)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	renderJSON(w, entries, http.StatusOK)
+}
+
+func (s *Server) getAdminTargetUser(r *http.Request) (*models.User, error) {
+	vars := mux.Vars(r)
+
+	userID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return models.GetUserByID(s.Database, userID)
+}
+
+// GrantAdmin makes the user with the given e-mail address an administrator, for bootstrapping the
+// first administrator from the command line
+func GrantAdmin(db *models.Database, email string) error {
+	user, err := models.GetUser(db, email)
+	if err != nil {
+		return err
+	}
+
+	user.Admin = true
+
+	return models.UpdateUser(db, user)
+}
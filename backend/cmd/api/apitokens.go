@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	apiTokenPrefix = "mnj_"
+	// apiTokenDisplayLength is how much of a token is kept in the clear to identify it
+	apiTokenDisplayLength = len(apiTokenPrefix) + 4
+	// apiTokenTouchInterval limits how often a token's last used time is written
+	apiTokenTouchInterval = time.Minute
+)
+
+type apiTokenCreatePayload struct {
+	Name   string
+	Scopes []string
+}
+
+func (atp *apiTokenCreatePayload) Validate() []string {
+	var errors []string
+
+	if strings.TrimSpace(atp.Name) == "" {
+		errors = append(errors, "Name must be given")
+	}
+
+	if len(atp.Scopes) == 0 {
+		errors = append(errors, "At least one scope must be given")
+	}
+
+	for _, scope := range atp.Scopes {
+		if !validAPITokenScope(scope) {
+			errors = append(errors, "Unknown scope "+scope)
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+type apiTokenCreateResponse struct {
+	*models.APIToken
+	Token string
+}
+
+// APITokenCreate creates a personal access token. The token is only ever shown in this response.
+func (s *Server) APITokenCreate(w http.ResponseWriter, r *http.Request) {
+	var payload apiTokenCreatePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	err = plans.Get(user.Plan).CheckAPITokens()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	secret, _, err := authservice.GenerateOpaqueToken()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := apiTokenPrefix + secret
+
+	apiToken := models.APIToken{
+		UserID:    user.ID,
+		Name:      payload.Name,
+		Prefix:    token[:apiTokenDisplayLength],
+		TokenHash: authservice.HashOpaqueToken(token),
+		Scopes:    payload.Scopes,
+	}
+
+	err = models.CreateAPIToken(s.Database, &apiToken)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, user.ID, models.AuditAPITokenCreated, "api_token", apiToken.ID.Hex(), apiToken.Name)
+
+	renderJSON(w, apiTokenCreateResponse{APIToken: &apiToken, Token: token}, http.StatusCreated)
+}
+
+// APITokenList lists the current user's personal access tokens
+func (s *Server) APITokenList(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	tokens, err := models.GetUserAPITokens(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, tokens, http.StatusOK)
+}
+
+// APITokenDelete revokes one of the current user's personal access tokens
+func (s *Server) APITokenDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	user := getUserFromContext(r)
+
+	tokenID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	err = models.DeleteUserAPIToken(s.Database, user.ID, tokenID)
+	if err != nil {
+		renderError(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	s.audit(r, user.ID, models.AuditAPITokenDeleted, "api_token", tokenID.Hex(), "")
+
+	renderOkay(w, http.StatusOK)
+}
+
+// authenticateBearerToken authenticates a request carrying a personal access token in its
+// Authorization header, checking the token has the scope the route needs
+func (s *Server) authenticateBearerToken(w http.ResponseWriter, r *http.Request, next http.Handler, bearer string) {
+	apiToken, err := models.GetAPIToken(s.Database, authservice.HashOpaqueToken(bearer))
+	if err != nil {
+		renderError(w, "Invalid API token", http.StatusUnauthorized)
+		return
+	}
+
+	scope := requiredScope(r)
+	if scope == "" || !apiToken.HasScope(scope) {
+		s.audit(r, apiToken.UserID, models.AuditAPITokenRejected, "api_token", apiToken.ID.Hex(), r.Method+" "+r.URL.Path)
+		renderError(w, "API token does not have permission for this request", http.StatusForbidden)
+		return
+	}
+
+	user, err := models.GetUserByID(s.Database, apiToken.UserID)
+	if err != nil {
+		renderError(w, "Could not find user", http.StatusForbidden)
+		return
+	}
+
+	if user.Disabled {
+		renderAccountDisabled(w)
+		return
+	}
+
+	err = plans.Get(user.Plan).CheckAPITokens()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if time.Since(apiToken.LastUsedAt) > apiTokenTouchInterval {
+		err = models.TouchAPIToken(s.Database, apiToken)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), "user", user)
+	ctx = context.WithValue(ctx, "apiToken", apiToken)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// requiredScope returns the scope an API token needs for the request. Routes which aren't listed,
+// such as account and token management, can't be used with an API token at all. Neither can
+// sharing a vehicle with other users or publishing share links, which give others access.
+func requiredScope(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api")
+
+	switch {
+	case sharingPath(path):
+		return ""
+	case strings.HasPrefix(path, "/vehicles"):
+		if r.Method == http.MethodGet {
+			return models.ScopeVehiclesRead
+		}
+		return models.ScopeVehiclesManage
+	case strings.HasPrefix(path, "/notifications"):
+		return models.ScopeNotifications
+	}
+
+	return ""
+}
+
+// sharingPath is true for the share and share link routes of a vehicle,
+// /vehicles/{registration}/shares and /vehicles/{registration}/links
+func sharingPath(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	return len(segments) >= 3 && segments[0] == "vehicles" && (segments[2] == "shares" || segments[2] == "links")
+}
+
+// bearerToken returns a personal access token from the Authorization header, if there is one
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")), true
+}
+
+func validAPITokenScope(scope string) bool {
+	for _, valid := range models.APITokenScopes {
+		if scope == valid {
+			return true
+		}
+	}
+
+	return false
+}
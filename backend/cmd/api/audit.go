@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// accountAuditEntries is the number of audit entries returned per page to users
+const accountAuditEntries = 50
+
+// AccountAuditList pages through the audit entries for the current user's own actions and those
+// against their account, such as failed logins, newest first. Pass the ID of the last entry seen
+// as ?before= to fetch the next page.
+func (s *Server) AccountAuditList(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	before, err := auditCursor(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := models.GetUserAuditEntries(s.Database, user.ID, before, accountAuditEntries)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	renderJSON(w, entries, http.StatusOK)
+}
+
+func auditCursor(r *http.Request) (primitive.ObjectID, error) {
+	before := r.URL.Query().Get("before")
+	if before == "" {
+		return primitive.NilObjectID, nil
+	}
+
+	cursor, err := primitive.ObjectIDFromHex(before)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("Invalid cursor %q", before)
+	}
+
+	return cursor, nil
+}
+
+// audit appends an entry to the audit log for an action taken by actor. Failing to write the log
+// doesn't fail the request.
+func (s *Server) audit(r *http.Request, actorID primitive.ObjectID, action, targetType, targetID, details string) {
+	entry := models.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IPAddress:  s.clientIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+
+	err := models.CreateAuditEntry(s.Database, &entry)
+	if err != nil {
+		log.Println(err)
+	}
+}
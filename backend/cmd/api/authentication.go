@@ -3,14 +3,22 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
 	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const jwtCookieName = "jwt"
 
+// sessionTouchInterval limits how often a session's last seen time is written
+const sessionTouchInterval = time.Minute
+
 type loginPayload struct {
 	Email    string
 	Password string
@@ -25,32 +33,85 @@ func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wait, err := s.loginRetryAfter(r, payload.Email)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wait > 0 {
+		renderThrottled(w, wait)
+		return
+	}
+
 	user, err := models.GetUser(s.Database, payload.Email)
 	if err != nil {
+		s.recordLoginFailure(r, payload.Email, nil)
 		renderBadUsernamePassword(w)
 		return
 	}
 
 	if !checkPassword(payload.Password, user.HashedPassword) {
+		s.recordLoginFailure(r, payload.Email, user)
 		renderBadUsernamePassword(w)
 		return
 	}
 
-	accessToken, err := s.AuthService.GenerateAccessToken(user)
+	if user.EmailUnverified {
+		renderError(w, "E-mail address has not been verified", http.StatusForbidden)
+		return
+	}
+
+	if user.Disabled {
+		renderAccountDisabled(w)
+		return
+	}
+
+	if user.TwoFactor.Enabled {
+		challengeToken, err := s.AuthService.GenerateTwoFactorChallengeToken(user)
+		if err != nil {
+			renderError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		renderJSON(w, twoFactorChallengeResponse{TwoFactorRequired: true, ChallengeToken: challengeToken}, http.StatusOK)
+		return
+	}
+
+	s.recordLoginSuccess(user.Email)
+
+	err = s.startSession(w, r, user)
 	if err != nil {
 		renderError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     jwtCookieName,
-		Value:    accessToken,
-		HttpOnly: true,
-	})
-
 	renderOkay(w, http.StatusOK)
 }
 
+// startSession creates a new session for the user and sets its token cookies
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	session := models.Session{
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IPAddress: s.clientIP(r),
+		ExpiresAt: time.Now().Add(s.AuthService.SessionLifetime()),
+	}
+
+	err := models.CreateSession(s.Database, &session)
+	if err != nil {
+		return err
+	}
+
+	s.audit(r, user.ID, models.AuditLogin, "session", session.ID.Hex(), "")
+
+	return s.issueTokens(w, user, &session)
+}
+
+func renderAccountDisabled(w http.ResponseWriter) {
+	renderError(w, "Account has been disabled", http.StatusForbidden)
+}
+
 func renderBadUsernamePassword(w http.ResponseWriter) {
 	renderError(w, "Incorrect email or password", http.StatusForbidden)
 }
@@ -60,38 +121,131 @@ func checkPassword(password, hash string) bool {
 	return err == nil
 }
 
+// recentLoginWindow is how recently a user without a password, who signs in through an identity
+// provider, must have done so to confirm a sensitive change
+const recentLoginWindow = 10 * time.Minute
+
+// reauthenticate checks the current user again before a sensitive change: by their password, or if
+// they don't have one by having signed in within the last few minutes. The failure has been
+// rendered when it returns false.
+func (s *Server) reauthenticate(w http.ResponseWriter, r *http.Request, password string) bool {
+	user := getUserFromContext(r)
+
+	if user.HashedPassword == "" {
+		if !recentlySignedIn(getSessionFromContext(r), time.Now()) {
+			renderError(w, "Please sign in again to confirm this change", http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+
+	if !checkPassword(password, user.HashedPassword) {
+		renderBadUsernamePassword(w)
+		return false
+	}
+
+	return true
+}
+
+// recentlySignedIn reports whether a session was started within recentLoginWindow of now. Requests
+// made with an API token have no session, and never count as a recent sign in.
+func recentlySignedIn(session *models.Session, now time.Time) bool {
+	return session != nil && now.Sub(session.CreatedAt) < recentLoginWindow
+}
+
+// Logout revokes the current session and clears the cookies. The refresh token is used to find
+// the session if the access token has already expired. It needs a CSRF token like any other
+// change, so another site can't sign users out.
 func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     jwtCookieName,
-		Value:    "",
-		HttpOnly: true,
-	})
+	if !s.checkCSRF(r) {
+		renderCSRFFailure(w)
+		return
+	}
+
+	var userID primitive.ObjectID
+
+	jwtCookie, err := r.Cookie(jwtCookieName)
+	if err == nil {
+		jwtClaim, err := s.AuthService.VerifyAccessToken(jwtCookie.Value)
+		if err == nil {
+			s.revokeTokenSession(jwtClaim)
+			userID, _ = jwtClaim.UserID()
+		}
+	}
+
+	refreshCookie, err := r.Cookie(refreshCookieName)
+	if err == nil {
+		refreshToken, err := models.GetRefreshToken(s.Database, authservice.HashOpaqueToken(refreshCookie.Value))
+		if err == nil {
+			s.revokeTokenFamily(refreshToken)
+			userID = refreshToken.UserID
+		}
+	}
+
+	s.clearAuthCookies(w)
+
+	if !userID.IsZero() {
+		s.audit(r, userID, models.AuditLogout, "user", userID.Hex(), "")
+	}
 
 	renderOkay(w, http.StatusOK)
 }
 
+// AuthJwtTokenMiddleware authenticates requests with either a personal access token sent as a
+// bearer token, or the access token cookie set at login. Only cookie authenticated requests need a
+// CSRF token, as a browser never attaches a bearer token by itself.
 func (s *Server) AuthJwtTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bearer, ok := bearerToken(r); ok {
+			s.authenticateBearerToken(w, r, next, bearer)
+			return
+		}
+
 		jwtCookie, err := r.Cookie(jwtCookieName)
 
 		if err != nil {
-			renderError(w, "Missing JWT token", http.StatusForbidden)
+			renderError(w, "Missing JWT token", http.StatusUnauthorized)
+			return
+		}
+
+		if !s.checkCSRF(r) {
+			renderCSRFFailure(w)
 			return
 		}
 
 		jwtClaim, err := s.AuthService.VerifyAccessToken(jwtCookie.Value)
 		if err != nil {
-			renderError(w, "Invalid JWT token", http.StatusForbidden)
+			renderError(w, "Invalid JWT token", http.StatusUnauthorized)
 			return
 		}
 
-		user, err := models.GetUser(s.Database, jwtClaim.UserID)
+		user, err := s.getTokenUser(jwtClaim)
 		if err != nil {
 			renderError(w, "Could not find user", http.StatusForbidden)
 			return
 		}
 
+		if user.Disabled {
+			renderAccountDisabled(w)
+			return
+		}
+
+		session, err := s.getTokenSession(jwtClaim, user)
+		if err != nil {
+			s.audit(r, user.ID, models.AuditSessionRejected, "session", jwtClaim.Id, r.Method+" "+r.URL.Path)
+			renderError(w, "Session has been revoked", http.StatusForbidden)
+			return
+		}
+
+		if time.Since(session.LastSeenAt) > sessionTouchInterval {
+			err = models.TouchSession(s.Database, session)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), "user", user)
+		ctx = context.WithValue(ctx, "session", session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -99,3 +253,56 @@ func (s *Server) AuthJwtTokenMiddleware(next http.Handler) http.Handler {
 func getUserFromContext(r *http.Request) *models.User {
 	return r.Context().Value("user").(*models.User)
 }
+
+// getSessionFromContext returns the session the request was made with, or nil if it was
+// authenticated with an API token
+func getSessionFromContext(r *http.Request) *models.Session {
+	session, _ := r.Context().Value("session").(*models.Session)
+	return session
+}
+
+// getTokenUser returns the user an access token was issued to
+func (s *Server) getTokenUser(claim *authservice.TokenClaim) (*models.User, error) {
+	userID, err := claim.UserID()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.GetUserByID(s.Database, userID)
+}
+
+// getTokenSession returns the active session an access token was issued for
+func (s *Server) getTokenSession(claim *authservice.TokenClaim, user *models.User) (*models.Session, error) {
+	sessionID, err := primitive.ObjectIDFromHex(claim.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := models.GetSession(s.Database, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserID != user.ID || !session.Active() {
+		return nil, errors.New("Session is not active")
+	}
+
+	return session, nil
+}
+
+func (s *Server) revokeTokenSession(claim *authservice.TokenClaim) {
+	user, err := s.getTokenUser(claim)
+	if err != nil {
+		return
+	}
+
+	session, err := s.getTokenSession(claim, user)
+	if err != nil {
+		return
+	}
+
+	err = models.RevokeUserSession(s.Database, user.ID, session.ID)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+func TestRecentlySignedIn(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		session *models.Session
+		recent  bool
+	}{
+		{name: "no session", session: nil, recent: false},
+		{name: "just signed in", session: &models.Session{CreatedAt: now.Add(-time.Minute)}, recent: true},
+		{name: "at the window", session: &models.Session{CreatedAt: now.Add(-recentLoginWindow)}, recent: false},
+		{name: "signed in yesterday", session: &models.Session{CreatedAt: now.Add(-24 * time.Hour)}, recent: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := recentlySignedIn(tc.session, now); got != tc.recent {
+				t.Errorf("Expected recent sign in %t but got %t", tc.recent, got)
+			}
+		})
+	}
+}
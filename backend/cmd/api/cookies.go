@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CookieSettings holds the attributes applied to every authentication cookie
+type CookieSettings struct {
+	Secure   bool
+	SameSite http.SameSite
+	Path     string
+}
+
+// ParseSameSite converts a configured SameSite mode of lax, strict or none
+func ParseSameSite(mode string) (http.SameSite, error) {
+	switch strings.ToLower(mode) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("Unknown SameSite mode %q", mode)
+	}
+}
+
+// setCookie sets a cookie with the configured attributes. A zero expiry makes a session cookie.
+func (s *Server) setCookie(w http.ResponseWriter, name, value string, expires time.Time, httpOnly bool) {
+	path := s.Cookies.Path
+	if path == "" {
+		path = "/"
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		Secure:   s.Cookies.Secure,
+		SameSite: s.Cookies.SameSite,
+		HttpOnly: httpOnly,
+	})
+}
+
+// clearCookie removes a cookie set by setCookie
+func (s *Server) clearCookie(w http.ResponseWriter, name string) {
+	path := s.Cookies.Path
+	if path == "" {
+		path = "/"
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     path,
+		MaxAge:   -1,
+		Secure:   s.Cookies.Secure,
+		SameSite: s.Cookies.SameSite,
+		HttpOnly: true,
+	})
+}
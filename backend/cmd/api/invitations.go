@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// invitationLifetime is how long an invitation to join an organisation remains valid
+const invitationLifetime = 7 * 24 * time.Hour
+
+type invitationCreatePayload struct {
+	Email string
+	Role  string
+}
+
+func (icp *invitationCreatePayload) Validate(actor *models.Membership) []string {
+	var errors []string
+
+	icp.Email = strings.TrimSpace(icp.Email)
+	validEmail, _ := regexp.MatchString(`^.+?@.+?\..+?$`, icp.Email)
+	if !validEmail {
+		errors = append(errors, "E-mail address is not valid")
+	}
+
+	if !validRole(icp.Role) {
+		errors = append(errors, "Role is not valid")
+	} else if !canAssignRole(actor, icp.Role) {
+		errors = append(errors, "You cannot invite someone with this role")
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+// InvitationList lists the outstanding invitations to an organisation
+func (s *Server) InvitationList(w http.ResponseWriter, r *http.Request) {
+	organisation, membership, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !membership.CanManageMembers() {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	invitations, err := models.GetOrganisationInvitations(s.Database, organisation.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if invitations == nil {
+		invitations = []*models.Invitation{}
+	}
+
+	renderJSON(w, invitations, http.StatusOK)
+}
+
+// InvitationCreate e-mails an invitation to join an organisation
+func (s *Server) InvitationCreate(w http.ResponseWriter, r *http.Request) {
+	var payload invitationCreatePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organisation, membership, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !membership.CanManageMembers() {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	validationErrors := payload.Validate(membership)
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	token, tokenHash, err := authservice.GenerateOpaqueToken()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	invitation := models.Invitation{
+		OrganisationID: organisation.ID,
+		Email:          payload.Email,
+		Role:           payload.Role,
+		InvitedBy:      membership.UserID,
+		TokenHash:      tokenHash,
+		ExpiresAt:      time.Now().Add(invitationLifetime),
+	}
+
+	err = models.CreateInvitation(s.Database, &invitation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	link := fmt.Sprintf("%s/invitations/accept?token=%s", s.BaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("You have been invited to join %s on MOT.ninja as %s %s.\n\nTo accept, sign in or create an account with this e-mail address and visit the link below:\n\n%s\n\nThis invitation expires in 7 days.\n", organisation.Name, articleFor(payload.Role), payload.Role, link)
+
+	// An invitation nobody received is withdrawn, so trying again doesn't leave a second live token
+	err = s.Mailer.Send(invitation.Email, fmt.Sprintf("Invitation to join %s", organisation.Name), body)
+	if err != nil {
+		deleteErr := models.DeleteOrganisationInvitation(s.Database, organisation.ID, invitation.ID)
+		if deleteErr != nil {
+			log.Println(deleteErr)
+		}
+
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, &invitation, http.StatusCreated)
+}
+
+// InvitationDelete withdraws an invitation
+func (s *Server) InvitationDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	organisation, membership, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !membership.CanManageMembers() {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	err = models.DeleteOrganisationInvitation(s.Database, organisation.ID, invitationID)
+	if err != nil {
+		renderError(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+type invitationAcceptPayload struct {
+	Token string
+}
+
+// InvitationAccept adds the current user to the organisation they were invited to. The invitation
+// must have been sent to the user's own e-mail address.
+func (s *Server) InvitationAccept(w http.ResponseWriter, r *http.Request) {
+	var payload invitationAcceptPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if user.EmailUnverified {
+		renderError(w, "Please verify your e-mail address before accepting invitations", http.StatusForbidden)
+		return
+	}
+
+	tokenHash := authservice.HashOpaqueToken(payload.Token)
+
+	// Check the address before consuming, so someone else can't use up the invitation
+	invitation, err := models.GetInvitation(s.Database, tokenHash)
+	if err != nil || !strings.EqualFold(invitation.Email, user.Email) {
+		renderError(w, "Invitation is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	organisation, err := models.GetOrganisation(s.Database, invitation.OrganisationID)
+	if err != nil {
+		renderError(w, "Invitation is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err = models.GetMembership(s.Database, organisation.ID, user.ID)
+	if err == nil {
+		renderError(w, "You are already a member of this organisation", http.StatusUnprocessableEntity)
+		return
+	}
+
+	invitation, err = models.ConsumeInvitation(s.Database, tokenHash)
+	if err != nil {
+		renderError(w, "Invitation is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	membership := models.Membership{
+		OrganisationID: organisation.ID,
+		UserID:         user.ID,
+		Role:           invitation.Role,
+	}
+
+	err = models.CreateMembership(s.Database, &membership)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, organisationResponse{Organisation: organisation, Role: membership.Role}, http.StatusOK)
+}
+
+func articleFor(role string) string {
+	if role == models.RoleOwner || role == models.RoleAdmin {
+		return "an"
+	}
+	return "a"
+}
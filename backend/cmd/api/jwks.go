@@ -0,0 +1,8 @@
+package api
+
+import "net/http"
+
+// JWKS publishes the public keys tokens may be verified with
+func (s *Server) JWKS(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, s.AuthService.Keys.JWKS(), http.StatusOK)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/registration"
+	"github.com/darkphnx/vehiclemanager/internal/usecases"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// lookupCacheLifetime is how long fetched details are reused for further lookups
+	lookupCacheLifetime = time.Hour
+	// lookupWindow is the period lookup rate limits are counted over
+	lookupWindow = time.Hour
+	// anonymousLookupLimit is the number of lookups an IP address may make per window without
+	// logging in
+	anonymousLookupLimit = 5
+	// userLookupLimit is the number of lookups a logged in user may make per window
+	userLookupLimit = 30
+)
+
+type lookupResponse struct {
+	*usecases.VehicleReport
+	Cached bool
+}
+
+// Lookup reports on a registration without adding it to an account. It can be used anonymously,
+// with lower rate limits than for logged in users.
+func (s *Server) Lookup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	reg, err := registration.Parse(vars["registration"])
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	key, limit := "lookup:ip:"+s.clientIP(r), int64(anonymousLookupLimit)
+	if user := s.optionalUser(r); user != nil {
+		key, limit = "lookup:user:"+user.ID.Hex(), int64(userLookupLimit)
+	}
+
+	rateLimit, err := models.HitRateLimit(s.Database, key, lookupWindow)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if rateLimit.Requests > limit {
+		wait := time.Until(rateLimit.ResetsAt(lookupWindow))
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		renderError(w, "Too many lookups, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	cached, err := models.GetCachedLookup(s.Database, reg.Number, lookupCacheLifetime)
+	if err == nil {
+		renderJSON(w, lookupResponse{VehicleReport: usecases.BuildVehicleReport(&cached.Vehicle), Cached: true}, http.StatusOK)
+		return
+	}
+
+	vehicleDetails := usecases.VehicleDetails{
+		VehicleEnquiryServiceAPI: s.VehicleEnquiryServiceAPI,
+		MotHistoryAPI:            s.MotHistoryAPI,
+	}
+	vehicle, err := vehicleDetails.Fetch(reg.Number)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = models.SaveCachedLookup(s.Database, &models.CachedLookup{RegistrationNumber: reg.Number, Vehicle: *vehicle})
+	if err != nil {
+		log.Println(err)
+	}
+
+	renderJSON(w, lookupResponse{VehicleReport: usecases.BuildVehicleReport(vehicle)}, http.StatusOK)
+}
+
+// optionalUser returns the user a request is authenticated as, or nil for an anonymous request.
+// Unlike AuthJwtTokenMiddleware, missing or invalid credentials are not an error. API tokens need
+// the vehicles:read scope to count.
+func (s *Server) optionalUser(r *http.Request) *models.User {
+	if bearer, ok := bearerToken(r); ok {
+		apiToken, err := models.GetAPIToken(s.Database, authservice.HashOpaqueToken(bearer))
+		if err != nil || !apiToken.HasScope(models.ScopeVehiclesRead) {
+			return nil
+		}
+
+		user, err := models.GetUserByID(s.Database, apiToken.UserID)
+		if err != nil || user.Disabled {
+			return nil
+		}
+
+		return user
+	}
+
+	jwtCookie, err := r.Cookie(jwtCookieName)
+	if err != nil {
+		return nil
+	}
+
+	jwtClaim, err := s.AuthService.VerifyAccessToken(jwtCookie.Value)
+	if err != nil {
+		return nil
+	}
+
+	user, err := s.getTokenUser(jwtClaim)
+	if err != nil || user.Disabled {
+		return nil
+	}
+
+	_, err = s.getTokenSession(jwtClaim, user)
+	if err != nil {
+		return nil
+	}
+
+	return user
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/notifier"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+)
+
+type notificationSettingsPayload struct {
+	Email      bool
+	WebhookURL string
+}
+
+func (nsp *notificationSettingsPayload) Validate(plan *plans.Plan) []string {
+	var errors []string
+
+	err := plan.CheckChannels(nsp.channels())
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if nsp.WebhookURL != "" {
+		err := notifier.ValidateWebhookURL(nsp.WebhookURL)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+func (nsp *notificationSettingsPayload) channels() []string {
+	var channels []string
+
+	if nsp.Email {
+		channels = append(channels, plans.ChannelEmail)
+	}
+
+	if nsp.WebhookURL != "" {
+		channels = append(channels, plans.ChannelWebhook)
+	}
+
+	return channels
+}
+
+// NotificationSettingsShow returns the current user's notification settings
+func (s *Server) NotificationSettingsShow(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	renderJSON(w, user.NotificationSettings, http.StatusOK)
+}
+
+// NotificationSettingsUpdate replaces the current user's notification settings
+func (s *Server) NotificationSettingsUpdate(w http.ResponseWriter, r *http.Request) {
+	var payload notificationSettingsPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	validationErrors := payload.Validate(plans.Get(user.Plan))
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+	user.NotificationSettings = models.NotificationSettings{
+		Email:      payload.Email,
+		WebhookURL: payload.WebhookURL,
+	}
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, user.NotificationSettings, http.StatusOK)
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/oidc"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const oidcStateCookieName = "oidc_state"
+
+// twoFactorChallengeCookieName holds the challenge token for single sign-on users with two factor
+// authentication while they enter their code. A cookie keeps it out of the URL, where it could
+// leak through history, logs or the Referer header.
+const twoFactorChallengeCookieName = "2fa_challenge"
+
+// OIDCLogin redirects the user to the identity provider to sign in
+func (s *Server) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	state, err := oidc.NewState()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	nonce, err := oidc.NewState()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := s.OIDCProvider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	stateToken, err := s.AuthService.GenerateOIDCStateToken(state, nonce, codeVerifier)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    stateToken,
+		Path:     "/oidc",
+		Expires:  time.Now().Add(10 * time.Minute),
+		Secure:   s.Cookies.Secure,
+		HttpOnly: true,
+		// Lax is required whatever is configured, as the provider redirects back cross-site
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes a login with the identity provider. The identity is matched to a user by
+// a previous link, then by verified e-mail address, and if neither is found and just-in-time
+// provisioning is enabled a new user is created. Users with two factor authentication enabled are
+// sent to the login page to complete the same challenge as a password login.
+func (s *Server) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil {
+		renderError(w, "Login has expired, please try again", http.StatusForbidden)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Path: "/oidc", MaxAge: -1, Secure: s.Cookies.Secure, HttpOnly: true})
+
+	stateClaim, err := s.AuthService.VerifyOIDCStateToken(stateCookie.Value)
+	if err != nil || stateClaim.State != r.URL.Query().Get("state") {
+		renderError(w, "Login has expired, please try again", http.StatusForbidden)
+		return
+	}
+
+	if errorCode := r.URL.Query().Get("error"); errorCode != "" {
+		renderError(w, "Identity provider returned "+errorCode, http.StatusForbidden)
+		return
+	}
+
+	claims, err := s.OIDCProvider.Exchange(r.URL.Query().Get("code"), stateClaim.CodeVerifier, stateClaim.Nonce)
+	if err != nil {
+		log.Println(err)
+		renderError(w, "Could not sign in with identity provider", http.StatusForbidden)
+		return
+	}
+
+	user, err := s.findOrCreateOIDCUser(claims)
+	if err == errOIDCNoAccount || err == errOIDCTwoFactorLinkDenied {
+		renderError(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if user.Disabled {
+		renderAccountDisabled(w)
+		return
+	}
+
+	if user.TwoFactor.Enabled {
+		challengeToken, err := s.AuthService.GenerateTwoFactorChallengeToken(user)
+		if err != nil {
+			renderError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.setCookie(w, twoFactorChallengeCookieName, challengeToken, time.Now().Add(authservice.TwoFactorChallengeLifetime), true)
+		http.Redirect(w, r, "/login?two_factor=1", http.StatusFound)
+		return
+	}
+
+	err = s.startSession(w, r, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+var (
+	errOIDCNoAccount           = errors.New("No account is linked to this identity")
+	errOIDCTwoFactorLinkDenied = errors.New("An account with this e-mail address uses two factor authentication, please sign in with your password")
+)
+
+func (s *Server) findOrCreateOIDCUser(claims *oidc.Claims) (*models.User, error) {
+	identity := models.OIDCIdentity{Issuer: claims.Issuer, Subject: claims.Subject}
+
+	user, err := models.GetUserByOIDCIdentity(s.Database, identity)
+	if err == nil {
+		return user, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Only a verified address may be used to link to, or create, a local account
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, errOIDCNoAccount
+	}
+
+	// An account protected by a second factor isn't linked on the strength of the provider's word
+	// alone, or anyone controlling an identity with the same address could bypass it. An account
+	// which never verified the address may not belong to its owner at all, so it is linked only
+	// once everything its registrant could sign in with has been removed.
+	user, err = models.GetUser(s.Database, claims.Email)
+	if err == nil && user.EmailUnverified {
+		err = s.revokeUnverifiedCredentials(user)
+		if err != nil {
+			return nil, err
+		}
+	} else if err == nil && user.TwoFactor.Enabled {
+		return nil, errOIDCTwoFactorLinkDenied
+	}
+
+	if err == nil {
+		user.OIDCIdentity = identity
+		user.EmailUnverified = false
+		s.claimVehicleShares(user)
+		return user, models.UpdateUser(s.Database, user)
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if !s.OIDCJustInTime {
+		return nil, errOIDCNoAccount
+	}
+
+	// Users created here have no password, so can only sign in through the provider until they
+	// reset one
+	user = &models.User{
+		Email: claims.Email,
+		Plan:  plans.Free,
+		NotificationSettings: models.NotificationSettings{
+			Email: true,
+		},
+		OIDCIdentity: identity,
+	}
+
+	err = models.CreateUser(s.Database, user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.claimVehicleShares(user)
+
+	return user, nil
+}
+
+// revokeUnverifiedCredentials removes the password, second factor and every session and token from
+// an account whose address was never verified, before it is handed to the verified owner of the
+// address. Otherwise someone who registered with another person's address could keep signing in.
+func (s *Server) revokeUnverifiedCredentials(user *models.User) error {
+	user.HashedPassword = ""
+	user.TwoFactor = models.TwoFactor{}
+
+	err := models.DeleteUserSessions(s.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = models.DeleteUserRefreshTokens(s.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return models.DeleteUserAPITokens(s.Database, user.ID)
+}
@@ -0,0 +1,375 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type organisationPayload struct {
+	Name string
+}
+
+func (op *organisationPayload) Validate() []string {
+	var errors []string
+
+	op.Name = strings.TrimSpace(op.Name)
+	if len(op.Name) == 0 || len(op.Name) > 64 {
+		errors = append(errors, "Name must be between 1 and 64 characters in length")
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+type organisationResponse struct {
+	*models.Organisation
+	Role string
+}
+
+// OrganisationList returns the organisations the current user is a member of
+func (s *Server) OrganisationList(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	memberships, err := models.GetUserMemberships(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := []organisationResponse{}
+	for _, membership := range memberships {
+		organisation, err := models.GetOrganisation(s.Database, membership.OrganisationID)
+		if err != nil {
+			continue
+		}
+
+		response = append(response, organisationResponse{Organisation: organisation, Role: membership.Role})
+	}
+
+	renderJSON(w, response, http.StatusOK)
+}
+
+// OrganisationCreate creates an organisation with the current user as its owner
+func (s *Server) OrganisationCreate(w http.ResponseWriter, r *http.Request) {
+	var payload organisationPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	user := getUserFromContext(r)
+	plan := plans.Get(user.Plan)
+
+	// Each organisation has its own vehicle limit, so the number a plan may own is limited too
+	err = plan.CheckOrganisationCount(models.UserOwnedOrganisationCount(s.Database, user.ID))
+	if err != nil {
+		renderError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Organisations start on their creator's plan, so can't be used to get more out of it than an
+	// account of their own
+	organisation := models.Organisation{
+		Name: payload.Name,
+		Plan: plan.Name,
+	}
+
+	err = models.CreateOrganisation(s.Database, &organisation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	membership := models.Membership{
+		OrganisationID: organisation.ID,
+		UserID:         user.ID,
+		Role:           models.RoleOwner,
+	}
+
+	err = models.CreateMembership(s.Database, &membership)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, organisationResponse{Organisation: &organisation, Role: membership.Role}, http.StatusCreated)
+}
+
+// OrganisationShow returns an organisation the current user is a member of
+func (s *Server) OrganisationShow(w http.ResponseWriter, r *http.Request) {
+	organisation, membership, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	renderJSON(w, organisationResponse{Organisation: organisation, Role: membership.Role}, http.StatusOK)
+}
+
+// OrganisationUpdate renames an organisation
+func (s *Server) OrganisationUpdate(w http.ResponseWriter, r *http.Request) {
+	var payload organisationPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organisation, membership, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !membership.CanManageMembers() {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	organisation.Name = payload.Name
+
+	err = models.UpdateOrganisation(s.Database, organisation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, organisationResponse{Organisation: organisation, Role: membership.Role}, http.StatusOK)
+}
+
+// OrganisationDelete deletes an organisation along with its vehicles, members and invitations
+func (s *Server) OrganisationDelete(w http.ResponseWriter, r *http.Request) {
+	organisation, membership, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if membership.Role != models.RoleOwner {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	err = models.PurgeOrganisation(s.Database, organisation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+type memberResponse struct {
+	*models.Membership
+	Email string
+}
+
+// OrganisationMemberList lists the members of an organisation
+func (s *Server) OrganisationMemberList(w http.ResponseWriter, r *http.Request) {
+	organisation, _, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	memberships, err := models.GetOrganisationMemberships(s.Database, organisation.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := []memberResponse{}
+	for _, membership := range memberships {
+		member, err := models.GetUserByID(s.Database, membership.UserID)
+		if err != nil {
+			continue
+		}
+
+		response = append(response, memberResponse{Membership: membership, Email: member.Email})
+	}
+
+	renderJSON(w, response, http.StatusOK)
+}
+
+type memberUpdatePayload struct {
+	Role string
+}
+
+// OrganisationMemberUpdate changes a member's role
+func (s *Server) OrganisationMemberUpdate(w http.ResponseWriter, r *http.Request) {
+	var payload memberUpdatePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organisation, actor, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	member, err := s.getOrganisationMember(r, organisation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !validRole(payload.Role) {
+		renderError(w, "Role is not valid", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !canAssignRole(actor, member.Role) || !canAssignRole(actor, payload.Role) {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	if removesLastOwner(member.Role, payload.Role, models.OrganisationOwnerCount(s.Database, organisation.ID)) {
+		renderError(w, "An organisation must always have an owner", http.StatusUnprocessableEntity)
+		return
+	}
+
+	member.Role = payload.Role
+
+	err = models.UpdateMembership(s.Database, member)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, member, http.StatusOK)
+}
+
+// OrganisationMemberRemove removes a member from an organisation. Any member may remove themselves.
+func (s *Server) OrganisationMemberRemove(w http.ResponseWriter, r *http.Request) {
+	organisation, actor, err := s.getOrganisationMembership(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	member, err := s.getOrganisationMember(r, organisation)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if member.ID != actor.ID && !canAssignRole(actor, member.Role) {
+		renderCannotManageOrganisation(w)
+		return
+	}
+
+	if removesLastOwner(member.Role, "", models.OrganisationOwnerCount(s.Database, organisation.ID)) {
+		renderError(w, "An organisation must always have an owner", http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = models.DeleteMembership(s.Database, member)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+// getOrganisationMembership loads the organisation named by the {organisation} route variable,
+// along with the current user's membership of it
+func (s *Server) getOrganisationMembership(r *http.Request) (*models.Organisation, *models.Membership, error) {
+	vars := mux.Vars(r)
+	user := getUserFromContext(r)
+
+	organisationID, err := primitive.ObjectIDFromHex(vars["organisation"])
+	if err != nil {
+		return nil, nil, errOrganisationNotFound
+	}
+
+	membership, err := models.GetMembership(s.Database, organisationID, user.ID)
+	if err != nil {
+		return nil, nil, errOrganisationNotFound
+	}
+
+	organisation, err := models.GetOrganisation(s.Database, organisationID)
+	if err != nil {
+		return nil, nil, errOrganisationNotFound
+	}
+
+	return organisation, membership, nil
+}
+
+// getOrganisationMember loads the membership of the user named by the {user} route variable
+func (s *Server) getOrganisationMember(r *http.Request, organisation *models.Organisation) (*models.Membership, error) {
+	vars := mux.Vars(r)
+
+	userID, err := primitive.ObjectIDFromHex(vars["user"])
+	if err != nil {
+		return nil, errMemberNotFound
+	}
+
+	membership, err := models.GetMembership(s.Database, organisation.ID, userID)
+	if err != nil {
+		return nil, errMemberNotFound
+	}
+
+	return membership, nil
+}
+
+// canAssignRole reports whether a member may grant a role, or change a member who holds it.
+// Owners may do anything; admins may only manage members and viewers.
+func canAssignRole(actor *models.Membership, role string) bool {
+	switch actor.Role {
+	case models.RoleOwner:
+		return true
+	case models.RoleAdmin:
+		return role == models.RoleMember || role == models.RoleViewer
+	default:
+		return false
+	}
+}
+
+// removesLastOwner reports whether changing a member's role to newRole, or removing them when
+// newRole is empty, would leave an organisation with ownerCount owners without any
+func removesLastOwner(role, newRole string, ownerCount int64) bool {
+	return role == models.RoleOwner && newRole != models.RoleOwner && ownerCount <= 1
+}
+
+func validRole(role string) bool {
+	for _, valid := range models.Roles {
+		if role == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+func renderCannotManageOrganisation(w http.ResponseWriter) {
+	renderError(w, "You do not have permission to manage this organisation", http.StatusForbidden)
+}
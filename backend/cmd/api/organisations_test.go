@@ -0,0 +1,91 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+func TestCanAssignRole(t *testing.T) {
+	testCases := []struct {
+		actor   string
+		role    string
+		allowed bool
+	}{
+		{actor: models.RoleOwner, role: models.RoleOwner, allowed: true},
+		{actor: models.RoleOwner, role: models.RoleAdmin, allowed: true},
+		{actor: models.RoleOwner, role: models.RoleMember, allowed: true},
+		{actor: models.RoleOwner, role: models.RoleViewer, allowed: true},
+		{actor: models.RoleAdmin, role: models.RoleOwner, allowed: false},
+		{actor: models.RoleAdmin, role: models.RoleAdmin, allowed: false},
+		{actor: models.RoleAdmin, role: models.RoleMember, allowed: true},
+		{actor: models.RoleAdmin, role: models.RoleViewer, allowed: true},
+		{actor: models.RoleMember, role: models.RoleOwner, allowed: false},
+		{actor: models.RoleMember, role: models.RoleAdmin, allowed: false},
+		{actor: models.RoleMember, role: models.RoleMember, allowed: false},
+		{actor: models.RoleMember, role: models.RoleViewer, allowed: false},
+		{actor: models.RoleViewer, role: models.RoleOwner, allowed: false},
+		{actor: models.RoleViewer, role: models.RoleAdmin, allowed: false},
+		{actor: models.RoleViewer, role: models.RoleMember, allowed: false},
+		{actor: models.RoleViewer, role: models.RoleViewer, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.actor+" assigns "+tc.role, func(t *testing.T) {
+			actor := models.Membership{Role: tc.actor}
+			if got := canAssignRole(&actor, tc.role); got != tc.allowed {
+				t.Errorf("Expected %s assigning %s to be allowed %t but got %t", tc.actor, tc.role, tc.allowed, got)
+			}
+		})
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	testCases := []struct {
+		role  string
+		valid bool
+	}{
+		{role: models.RoleOwner, valid: true},
+		{role: models.RoleAdmin, valid: true},
+		{role: models.RoleMember, valid: true},
+		{role: models.RoleViewer, valid: true},
+		{role: "", valid: false},
+		{role: "Owner", valid: false},
+		{role: "superuser", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.role, func(t *testing.T) {
+			if got := validRole(tc.role); got != tc.valid {
+				t.Errorf("Expected '%s' valid %t but got %t", tc.role, tc.valid, got)
+			}
+		})
+	}
+}
+
+func TestRemovesLastOwner(t *testing.T) {
+	testCases := []struct {
+		name       string
+		role       string
+		newRole    string
+		ownerCount int64
+		removes    bool
+	}{
+		{name: "demote only owner", role: models.RoleOwner, newRole: models.RoleAdmin, ownerCount: 1, removes: true},
+		{name: "remove only owner", role: models.RoleOwner, newRole: "", ownerCount: 1, removes: true},
+		{name: "keep only owner as owner", role: models.RoleOwner, newRole: models.RoleOwner, ownerCount: 1, removes: false},
+		{name: "demote one of two owners", role: models.RoleOwner, newRole: models.RoleViewer, ownerCount: 2, removes: false},
+		{name: "remove one of two owners", role: models.RoleOwner, newRole: "", ownerCount: 2, removes: false},
+		{name: "demote admin", role: models.RoleAdmin, newRole: models.RoleMember, ownerCount: 1, removes: false},
+		{name: "remove member", role: models.RoleMember, newRole: "", ownerCount: 1, removes: false},
+		{name: "promote viewer", role: models.RoleViewer, newRole: models.RoleOwner, ownerCount: 1, removes: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := removesLastOwner(tc.role, tc.newRole, tc.ownerCount); got != tc.removes {
+				t.Errorf("Expected removes last owner %t but got %t", tc.removes, got)
+			}
+		})
+	}
+}
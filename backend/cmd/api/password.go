@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+const (
+	// passwordResetLifetime is how long a password reset link remains valid
+	passwordResetLifetime = time.Hour
+	// passwordForgotWindow is the period password reset request limits are counted over
+	passwordForgotWindow = time.Hour
+	// passwordForgotAddressLimit is the number of reset e-mails an address may be sent per window
+	passwordForgotAddressLimit = 3
+	// passwordForgotIPLimit is the number of resets an IP address may request per window
+	passwordForgotIPLimit = 10
+)
+
+type passwordForgotPayload struct {
+	Email string
+}
+
+// PasswordForgot e-mails a password reset link. The response is the same whether or not the
+// address is registered. Each IP address may only make a few requests, and once an address has
+// been sent a few links further requests for it are answered without sending any more.
+func (s *Server) PasswordForgot(w http.ResponseWriter, r *http.Request) {
+	var payload passwordForgotPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ipLimit, err := models.HitRateLimit(s.Database, "password_forgot:ip:"+s.clientIP(r), passwordForgotWindow)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if ipLimit.Requests > passwordForgotIPLimit {
+		wait := time.Until(ipLimit.ResetsAt(passwordForgotWindow))
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		renderError(w, "Too many password reset requests, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// Counted whether or not the address is registered, so the limit doesn't reveal it either
+	addressLimit, err := models.HitRateLimit(s.Database, "password_forgot:email:"+strings.ToLower(strings.TrimSpace(payload.Email)), passwordForgotWindow)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if addressLimit.Requests > passwordForgotAddressLimit {
+		renderOkay(w, http.StatusOK)
+		return
+	}
+
+	user, err := models.GetUser(s.Database, payload.Email)
+	if err == nil {
+		// Send in the background so response time doesn't reveal that the address exists
+		go func() {
+			err := s.sendPasswordResetEmail(user)
+			if err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+type passwordResetPayload struct {
+	Token           string
+	Password        string
+	PasswordConfirm string
+}
+
+// PasswordReset sets a new password using a token from PasswordForgot and signs the user out
+// everywhere
+func (s *Server) PasswordReset(w http.ResponseWriter, r *http.Request) {
+	var payload passwordResetPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validationErrors := validatePassword(payload.Password, payload.PasswordConfirm)
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	reset, err := models.ConsumePasswordReset(s.Database, authservice.HashOpaqueToken(payload.Token))
+	if err != nil {
+		renderError(w, "Password reset link is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	user, err := models.GetUserByID(s.Database, reset.UserID)
+	if err != nil {
+		renderError(w, "Password reset link is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	hashedPassword, err := hashPassword(payload.Password)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user.HashedPassword = hashedPassword
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = models.RevokeUserSessions(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = models.DeleteUserPasswordResets(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, user.ID, models.AuditPasswordReset, "user", user.ID.Hex(), "")
+
+	renderOkay(w, http.StatusOK)
+}
+
+func (s *Server) sendPasswordResetEmail(user *models.User) error {
+	token, tokenHash, err := authservice.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(passwordResetLifetime),
+	}
+
+	err = models.CreatePasswordReset(s.Database, &reset)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/password/reset?token=%s", s.BaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("A password reset was requested for your MOT.ninja account.\n\nTo choose a new password visit the link below:\n\n%s\n\nThis link expires in one hour. If you didn't request a reset you can ignore this e-mail.\n", link)
+
+	return s.Mailer.Send(user.Email, "Reset your password", body)
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/registration"
+	"github.com/gorilla/mux"
+)
+
+// RegistrationMiddleware normalises the {registration} route variable so handlers always see the
+// canonical form. It doesn't validate it, so vehicles saved before registrations were checked can
+// still be reached; registrations are parsed where vehicles are created or looked up.
+func RegistrationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		registrationNumber, ok := vars["registration"]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		vars["registration"] = registration.Normalise(registrationNumber)
+		next.ServeHTTP(w, mux.SetURLVars(r, vars))
+	})
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP returns the address of the client making the request. X-Forwarded-For is only honoured
+// when the server is configured to sit behind a trusted proxy, as clients can set it freely.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.TrustProxyHeaders {
+		forwardedFor := r.Header.Get("X-Forwarded-For")
+		if forwardedFor != "" {
+			return strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sessionResponse struct {
+	*models.Session
+	Current bool
+}
+
+// SessionList returns the current user's active sessions
+func (s *Server) SessionList(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	currentSession := getSessionFromContext(r)
+
+	sessions, err := models.GetUserSessions(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := []sessionResponse{}
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			Session: session,
+			Current: currentSession != nil && session.ID == currentSession.ID,
+		})
+	}
+
+	renderJSON(w, response, http.StatusOK)
+}
+
+// SessionRevoke revokes one of the current user's sessions
+func (s *Server) SessionRevoke(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	user := getUserFromContext(r)
+
+	sessionID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	err = models.RevokeUserSession(s.Database, user.ID, sessionID)
+	if err != nil {
+		renderError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+// SessionRevokeAll revokes every one of the current user's sessions, including this one
+func (s *Server) SessionRevokeAll(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	err := models.RevokeUserSessions(s.Database, user.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	shareLinkPrefix = "mnjs_"
+	// shareLinkDisplayLength is how much of a link's token is kept in the clear to identify it
+	shareLinkDisplayLength = len(shareLinkPrefix) + 4
+	// shareLinkMaxExpiryDays is the furthest in the future a share link may expire
+	shareLinkMaxExpiryDays = 365
+)
+
+type shareLinkCreatePayload struct {
+	// ExpiresInDays is how long the link works for, zero for a link that never expires
+	ExpiresInDays int
+}
+
+func (slcp *shareLinkCreatePayload) Validate() []string {
+	var errors []string
+
+	if slcp.ExpiresInDays < 0 || slcp.ExpiresInDays > shareLinkMaxExpiryDays {
+		errors = append(errors, "Expiry must be between 0 and 365 days")
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+type shareLinkCreateResponse struct {
+	*models.ShareLink
+	URL string
+}
+
+// ShareLinkCreate creates a public link to a vehicle's history. The link is only ever shown in
+// this response.
+func (s *Server) ShareLinkCreate(w http.ResponseWriter, r *http.Request) {
+	var payload shareLinkCreatePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vehicle, ok := s.getLinkableVehicle(w, r)
+	if !ok {
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	secret, _, err := authservice.GenerateOpaqueToken()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := shareLinkPrefix + secret
+
+	link := models.ShareLink{
+		VehicleID: vehicle.ID,
+		CreatedBy: getUserFromContext(r).ID,
+		Prefix:    token[:shareLinkDisplayLength],
+		TokenHash: authservice.HashOpaqueToken(token),
+	}
+	if payload.ExpiresInDays > 0 {
+		link.ExpiresAt = time.Now().AddDate(0, 0, payload.ExpiresInDays)
+	}
+
+	err = models.CreateShareLink(s.Database, &link)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, link.CreatedBy, models.AuditShareLinkCreated, "vehicle", vehicle.ID.Hex(), link.Prefix)
+
+	renderJSON(w, shareLinkCreateResponse{ShareLink: &link, URL: s.BaseURL + "/share/" + token}, http.StatusCreated)
+}
+
+// ShareLinkList lists a vehicle's share links along with how often each has been viewed
+func (s *Server) ShareLinkList(w http.ResponseWriter, r *http.Request) {
+	vehicle, ok := s.getLinkableVehicle(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := models.GetVehicleShareLinks(s.Database, vehicle.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if links == nil {
+		links = []*models.ShareLink{}
+	}
+
+	renderJSON(w, links, http.StatusOK)
+}
+
+// ShareLinkDelete revokes a share link
+func (s *Server) ShareLinkDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	vehicle, ok := s.getLinkableVehicle(w, r)
+	if !ok {
+		return
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+
+	err = models.DeleteShareLink(s.Database, vehicle.ID, linkID)
+	if err != nil {
+		renderError(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+
+	s.audit(r, getUserFromContext(r).ID, models.AuditShareLinkRevoked, "vehicle", vehicle.ID.Hex(), linkID.Hex())
+
+	renderOkay(w, http.StatusOK)
+}
+
+// publicVehicle is the view of a vehicle given to anyone holding a share link. It leaves out
+// everything identifying the owner or our own records.
+type publicVehicle struct {
+	RegistrationNumber string
+	Manufacturer       string
+	Model              string
+	Colour             string
+	FuelType           string
+	YearOfManufacture  int
+	MotDue             time.Time
+	NoMotYet           bool
+	VEDDue             time.Time
+	TaxStatus          string
+	MotStatus          string
+	MOTHistory         []models.MOTTest
+	LastFetchedAt      time.Time
+}
+
+func newPublicVehicle(vehicle *models.Vehicle) *publicVehicle {
+	return &publicVehicle{
+		RegistrationNumber: vehicle.RegistrationNumber,
+		Manufacturer:       vehicle.Manufacturer,
+		Model:              vehicle.Model,
+		Colour:             vehicle.Colour,
+		FuelType:           vehicle.FuelType,
+		YearOfManufacture:  vehicle.YearOfManufacture,
+		MotDue:             vehicle.MotDue,
+		NoMotYet:           vehicle.NoMotYet,
+		VEDDue:             vehicle.VEDDue,
+		TaxStatus:          vehicle.TaxStatus,
+		MotStatus:          vehicle.MotStatus,
+		MOTHistory:         vehicle.MOTHistory,
+		LastFetchedAt:      vehicle.LastFetchedAt,
+	}
+}
+
+// SharedVehicle returns the public view of the vehicle a share link points to
+func (s *Server) SharedVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicle, ok := s.getSharedVehicle(r)
+	if !ok {
+		renderError(w, "Share link is invalid or has expired", http.StatusNotFound)
+		return
+	}
+
+	renderJSON(w, newPublicVehicle(vehicle), http.StatusOK)
+}
+
+// SharedVehiclePage renders the public view of the vehicle a share link points to as a page that
+// works without the UI
+func (s *Server) SharedVehiclePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	vehicle, ok := s.getSharedVehicle(r)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		sharedVehicleTemplate.Execute(w, nil)
+		return
+	}
+
+	err := sharedVehicleTemplate.Execute(w, newPublicVehicle(vehicle))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// getSharedVehicle loads the vehicle for the {token} route variable, counting the view
+func (s *Server) getSharedVehicle(r *http.Request) (*models.Vehicle, bool) {
+	vars := mux.Vars(r)
+
+	link, err := models.ViewShareLink(s.Database, authservice.HashOpaqueToken(vars["token"]))
+	if err != nil {
+		return nil, false
+	}
+
+	vehicle, err := models.GetVehicleByID(s.Database, link.VehicleID)
+	if err != nil {
+		return nil, false
+	}
+
+	return vehicle, true
+}
+
+// getLinkableVehicle loads the vehicle named in the route, rendering an error unless the current
+// user may manage its public links
+func (s *Server) getLinkableVehicle(w http.ResponseWriter, r *http.Request) (*models.Vehicle, bool) {
+	vars := mux.Vars(r)
+
+	scope, err := s.getVehicleScope(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+
+	vehicle, err := scope.vehicle(s.Database, vars["registration"])
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+
+	if !scope.canLinkVehicle(vehicle) {
+		renderError(w, "Only the owner can publish links to this vehicle", http.StatusForbidden)
+		return nil, false
+	}
+
+	return vehicle, true
+}
+
+var sharedVehicleTemplate = template.Must(template.New("shared").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("02/01/2006")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .}}{{.RegistrationNumber}} - {{end}}MOT.ninja</title>
+<style>
+body { font-family: sans-serif; color: #333; max-width: 60em; margin: 2em auto; padding: 0 1em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5em; }
+th, td { text-align: left; padding: 0.4em; border-bottom: 1px solid #ddd; vertical-align: top; }
+.passed { color: #2a7a2a; }
+.failed { color: #b22; }
+ul { margin: 0; padding-left: 1.2em; }
+</style>
+</head>
+<body>
+{{if .}}
+<h1>{{.RegistrationNumber}}</h1>
+<p>{{.Manufacturer}} {{.Model}}{{if .Colour}}, {{.Colour}}{{end}}{{if .FuelType}}, {{.FuelType}}{{end}}{{if .YearOfManufacture}}, {{.YearOfManufacture}}{{end}}</p>
+<table>
+<tr><th>MOT</th><td>{{if .NoMotYet}}Not yet required{{else}}{{.MotStatus}}{{end}}, due {{date .MotDue}}</td></tr>
+<tr><th>Tax</th><td>{{.TaxStatus}}, due {{date .VEDDue}}</td></tr>
+</table>
+<h2>MOT History</h2>
+{{if .MOTHistory}}
+<table>
+<tr><th>Date</th><th>Result</th><th>Mileage</th><th>Notes</th></tr>
+{{range .MOTHistory}}
+<tr>
+<td>{{date .CompletedDate}}</td>
+<td>{{if .Passed}}<span class="passed">Pass</span>{{else}}<span class="failed">Fail</span>{{end}}</td>
+<td>{{.OdometerReading}}</td>
+<td>{{if .RfrAndComments}}<ul>{{range .RfrAndComments}}<li>{{.Type}}: {{.Comment}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No MOT tests have been recorded.</p>
+{{end}}
+<p><small>Last updated {{date .LastFetchedAt}}</small></p>
+{{else}}
+<h1>Link unavailable</h1>
+<p>This share link is invalid, has expired or has been revoked.</p>
+{{end}}
+</body>
+</html>
+`))
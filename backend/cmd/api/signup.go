@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"regexp"
 
 	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,24 +21,8 @@ type signupPayload struct {
 func (sp *signupPayload) Validate(db *models.Database) []string {
 	var errors []string
 
-	validEmail, _ := regexp.MatchString(`^.+?@.+?\..+?$`, sp.Email)
-	if !validEmail {
-		errors = append(errors, "E-mail address is not valid")
-	}
-
-	emailExists := models.UserExists(db, sp.Email)
-	if emailExists {
-		errors = append(errors, "E-mail address is already registered")
-	}
-
-	validPassword, _ := regexp.MatchString(`^.{6,64}$`, sp.Password)
-	if !validPassword {
-		errors = append(errors, "Password must be between 6 and 64 characters in length")
-	}
-
-	if sp.Password != sp.PasswordConfirm {
-		errors = append(errors, "Password and confirmation must be the same")
-	}
+	errors = append(errors, validateEmail(db, sp.Email)...)
+	errors = append(errors, validatePassword(sp.Password, sp.PasswordConfirm)...)
 
 	if !sp.TermsAndConditions {
 		errors = append(errors, "Terms and Conditions must be agreed to")
@@ -73,7 +59,11 @@ func (s *Server) Signup(w http.ResponseWriter, r *http.Request) {
 	user := models.User{
 		Email:          payload.Email,
 		HashedPassword: hashedPassword,
-		VehicleLimit:   5,
+		Plan:           plans.Free,
+		NotificationSettings: models.NotificationSettings{
+			Email: true,
+		},
+		EmailUnverified: true,
 	}
 
 	err = models.CreateUser(s.Database, &user)
@@ -82,9 +72,49 @@ func (s *Server) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.audit(r, user.ID, models.AuditSignup, "user", user.ID.Hex(), "")
+
+	// The account exists now whether or not the mail went out, and the user can ask for it to be
+	// sent again
+	err = s.sendVerificationEmail(&user, user.Email)
+	if err != nil {
+		log.Println(err)
+	}
+
 	renderJSON(w, &user, http.StatusCreated)
 }
 
+func validateEmail(db *models.Database, email string) []string {
+	var errors []string
+
+	validEmail, _ := regexp.MatchString(`^.+?@.+?\..+?$`, email)
+	if !validEmail {
+		errors = append(errors, "E-mail address is not valid")
+	}
+
+	emailExists := models.UserExists(db, email)
+	if emailExists {
+		errors = append(errors, "E-mail address is already registered")
+	}
+
+	return errors
+}
+
+func validatePassword(password, passwordConfirm string) []string {
+	var errors []string
+
+	validPassword, _ := regexp.MatchString(`^.{6,64}$`, password)
+	if !validPassword {
+		errors = append(errors, "Password must be between 6 and 64 characters in length")
+	}
+
+	if password != passwordConfirm {
+		errors = append(errors, "Password and confirmation must be the same")
+	}
+
+	return errors
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
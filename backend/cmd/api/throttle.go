@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// throttlePolicy describes how failed logins are slowed down and eventually locked out. After
+// freeAttempts failures each further attempt must wait twice as long as the last, up to maxDelay,
+// and after lockoutAfter failures logins are refused for lockoutDuration.
+type throttlePolicy struct {
+	freeAttempts    int
+	maxDelay        time.Duration
+	lockoutAfter    int
+	lockoutDuration time.Duration
+	window          time.Duration
+}
+
+var (
+	accountThrottlePolicy = throttlePolicy{
+		freeAttempts:    3,
+		maxDelay:        time.Minute,
+		lockoutAfter:    10,
+		lockoutDuration: 15 * time.Minute,
+		window:          time.Hour,
+	}
+	ipThrottlePolicy = throttlePolicy{
+		freeAttempts:    10,
+		maxDelay:        time.Minute,
+		lockoutAfter:    50,
+		lockoutDuration: 15 * time.Minute,
+		window:          time.Hour,
+	}
+)
+
+// retryAfter returns how long until another attempt is allowed, or zero if one is allowed now
+func (p throttlePolicy) retryAfter(throttle *models.LoginThrottle) time.Duration {
+	if throttle.Locked() {
+		return time.Until(throttle.LockedUntil)
+	}
+
+	if throttle.Failures <= p.freeAttempts {
+		return 0
+	}
+
+	delay := time.Duration(math.Pow(2, float64(throttle.Failures-p.freeAttempts-1))) * time.Second
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+
+	wait := time.Until(throttle.LastFailureAt.Add(delay))
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+func ipThrottleKey(r *http.Request, s *Server) string {
+	return "ip:" + s.clientIP(r)
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginRetryAfter returns how long the client must wait before attempting to log in to the account
+func (s *Server) loginRetryAfter(r *http.Request, email string) (time.Duration, error) {
+	ipThrottle, err := models.GetLoginThrottle(s.Database, ipThrottleKey(r, s))
+	if err != nil {
+		return 0, err
+	}
+
+	accountThrottle, err := models.GetLoginThrottle(s.Database, accountThrottleKey(email))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait := ipThrottlePolicy.retryAfter(ipThrottle)
+	accountWait := accountThrottlePolicy.retryAfter(accountThrottle)
+
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+
+	return accountWait, nil
+}
+
+// recordLoginFailure counts a failed attempt against both the IP and the account, locking either
+// out once it passes its limit. The user is e-mailed when their account is locked. user may be nil
+// if the e-mail address isn't registered, which is throttled all the same.
+func (s *Server) recordLoginFailure(r *http.Request, email string, user *models.User) {
+	if user != nil {
+		s.audit(r, primitive.NilObjectID, models.AuditLoginFailed, "user", user.ID.Hex(), "")
+	} else {
+		s.audit(r, primitive.NilObjectID, models.AuditLoginFailed, "email", email, "")
+	}
+
+	s.recordThrottleFailure(ipThrottleKey(r, s), ipThrottlePolicy)
+
+	locked := s.recordThrottleFailure(accountThrottleKey(email), accountThrottlePolicy)
+	if locked && user != nil {
+		body := fmt.Sprintf("Your MOT.ninja account has been locked for %d minutes after too many failed login attempts.\n\nIf this wasn't you, we recommend resetting your password.\n", int(accountThrottlePolicy.lockoutDuration.Minutes()))
+
+		err := s.Mailer.Send(user.Email, "Your account has been locked", body)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// recordThrottleFailure returns true if this failure caused a lockout
+func (s *Server) recordThrottleFailure(key string, policy throttlePolicy) bool {
+	throttle, err := models.RecordLoginFailure(s.Database, key, policy.window)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	if throttle.Failures < policy.lockoutAfter || throttle.Locked() {
+		return false
+	}
+
+	err = models.LockLogin(s.Database, key, time.Now().Add(policy.lockoutDuration))
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	return true
+}
+
+// recordLoginSuccess clears the account's failures. The IP's failures are kept, otherwise an
+// attacker could reset them by logging in to an account of their own.
+func (s *Server) recordLoginSuccess(email string) {
+	err := models.ClearLoginThrottle(s.Database, accountThrottleKey(email))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// UnlockAccount lifts a lockout on an account, for use by administrators
+func UnlockAccount(db *models.Database, email string) error {
+	return models.ClearLoginThrottle(db, accountThrottleKey(email))
+}
+
+func renderThrottled(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+	renderError(w, "Too many failed login attempts, please try again later", http.StatusTooManyRequests)
+}
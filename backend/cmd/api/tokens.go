@@ -0,0 +1,140 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const refreshCookieName = "refresh_token"
+
+// TokenRefresh exchanges a refresh token for a new access token and a new refresh token. Presenting
+// a refresh token which has already been exchanged revokes the whole session, as either the
+// legitimate client or an attacker holds a stolen copy.
+func (s *Server) TokenRefresh(w http.ResponseWriter, r *http.Request) {
+	refreshCookie, err := r.Cookie(refreshCookieName)
+	if err != nil {
+		renderError(w, "Missing refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.checkCSRF(r) {
+		renderCSRFFailure(w)
+		return
+	}
+
+	refreshToken, err := models.GetRefreshToken(s.Database, authservice.HashOpaqueToken(refreshCookie.Value))
+	if err != nil || refreshToken.ExpiresAt.Before(time.Now()) {
+		s.clearAuthCookies(w)
+		renderError(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := models.GetSession(s.Database, refreshToken.SessionID)
+	if err != nil || !session.Active() {
+		s.clearAuthCookies(w)
+		renderError(w, "Session has been revoked", http.StatusUnauthorized)
+		return
+	}
+
+	err = models.UseRefreshToken(s.Database, refreshToken)
+	if err == models.ErrRefreshTokenReused {
+		s.revokeTokenFamily(refreshToken)
+		s.clearAuthCookies(w)
+		renderError(w, "Refresh token has already been used, session revoked", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := models.GetUserByID(s.Database, refreshToken.UserID)
+	if err != nil {
+		s.clearAuthCookies(w)
+		renderError(w, "Could not find user", http.StatusUnauthorized)
+		return
+	}
+
+	if user.Disabled {
+		s.clearAuthCookies(w)
+		renderAccountDisabled(w)
+		return
+	}
+
+	err = models.RenewSession(s.Database, session, time.Now().Add(s.AuthService.SessionLifetime()))
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.issueTokens(w, user, session)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+// issueTokens sets cookies containing a short-lived access token and a fresh refresh token for the
+// session
+func (s *Server) issueTokens(w http.ResponseWriter, user *models.User, session *models.Session) error {
+	token, tokenHash, err := authservice.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	refreshToken := models.RefreshToken{
+		SessionID: session.ID,
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: session.ExpiresAt,
+	}
+
+	err = models.CreateRefreshToken(s.Database, &refreshToken)
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := s.AuthService.GenerateAccessToken(user, session)
+	if err != nil {
+		return err
+	}
+
+	csrfToken, _, err := authservice.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	s.setCookie(w, jwtCookieName, accessToken, time.Time{}, true)
+	s.setCookie(w, refreshCookieName, token, session.ExpiresAt, true)
+
+	// The CSRF token is readable by our own scripts so they can send it back as a header
+	s.setCookie(w, csrfCookieName, csrfToken, session.ExpiresAt, false)
+
+	return nil
+}
+
+// revokeTokenFamily revokes the session a refresh token belongs to along with every refresh token
+// issued for it
+func (s *Server) revokeTokenFamily(refreshToken *models.RefreshToken) {
+	err := models.RevokeUserSession(s.Database, refreshToken.UserID, refreshToken.SessionID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Println(err)
+	}
+
+	err = models.DeleteSessionRefreshTokens(s.Database, refreshToken.SessionID)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (s *Server) clearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{jwtCookieName, refreshCookieName, csrfCookieName} {
+		s.clearCookie(w, name)
+	}
+}
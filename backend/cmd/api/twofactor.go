@@ -0,0 +1,291 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	totpIssuer        = "MOT.ninja"
+	recoveryCodeCount = 10
+)
+
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool
+	ChallengeToken    string
+}
+
+type loginTwoFactorPayload struct {
+	ChallengeToken string
+	Code           string
+}
+
+// LoginTwoFactor completes a login for a user with two factor authentication enabled, exchanging
+// the challenge token from Login and a TOTP or recovery code for a session. Single sign-on leaves
+// the challenge token in a cookie instead, which is used when none is sent.
+func (s *Server) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload loginTwoFactorPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if payload.ChallengeToken == "" {
+		challengeCookie, err := r.Cookie(twoFactorChallengeCookieName)
+		if err == nil {
+			payload.ChallengeToken = challengeCookie.Value
+		}
+	}
+
+	claim, err := s.AuthService.VerifyTwoFactorChallengeToken(payload.ChallengeToken)
+	if err != nil {
+		renderError(w, "Login has expired, please try again", http.StatusForbidden)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claim.Subject)
+	if err != nil {
+		renderError(w, "Login has expired, please try again", http.StatusForbidden)
+		return
+	}
+
+	user, err := models.GetUserByID(s.Database, userID)
+	if err != nil || !user.TwoFactor.Enabled {
+		renderError(w, "Login has expired, please try again", http.StatusForbidden)
+		return
+	}
+
+	if user.Disabled {
+		renderAccountDisabled(w)
+		return
+	}
+
+	wait, err := s.loginRetryAfter(r, user.Email)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wait > 0 {
+		renderThrottled(w, wait)
+		return
+	}
+
+	ok, err := s.checkSecondFactor(user, payload.Code)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		s.recordLoginFailure(r, user.Email, user)
+		renderError(w, "Incorrect authentication code", http.StatusForbidden)
+		return
+	}
+
+	s.recordLoginSuccess(user.Email)
+	s.clearCookie(w, twoFactorChallengeCookieName)
+
+	err = s.startSession(w, r, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+type twoFactorEnrolResponse struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type twoFactorEnrolPayload struct {
+	Password string
+}
+
+// TwoFactorEnrol generates a new TOTP secret for the user after checking it is them again. It isn't
+// used until confirmed with a code from the authenticator app.
+func (s *Server) TwoFactorEnrol(w http.ResponseWriter, r *http.Request) {
+	var payload twoFactorEnrolPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if user.TwoFactor.Enabled {
+		renderError(w, "Two factor authentication is already enabled", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !s.reauthenticate(w, r, payload.Password) {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user.TwoFactor.PendingSecret = secret
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := twoFactorEnrolResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, user.Email, secret),
+	}
+
+	renderJSON(w, response, http.StatusOK)
+}
+
+type twoFactorConfirmPayload struct {
+	Code string
+}
+
+type twoFactorConfirmResponse struct {
+	RecoveryCodes []string
+}
+
+// TwoFactorConfirm enables two factor authentication once the user proves their app generates
+// correct codes. Recovery codes are returned once and only their hashes are kept.
+func (s *Server) TwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	var payload twoFactorConfirmPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if user.TwoFactor.PendingSecret == "" {
+		renderError(w, "Two factor authentication enrolment has not been started", http.StatusUnprocessableEntity)
+		return
+	}
+
+	step, ok := totp.Validate(user.TwoFactor.PendingSecret, payload.Code, time.Now())
+	if !ok {
+		renderError(w, "Incorrect authentication code", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var recoveryCodes, recoveryCodeHashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, hash, err := authservice.GenerateOpaqueToken()
+		if err != nil {
+			renderError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		recoveryCodes = append(recoveryCodes, code)
+		recoveryCodeHashes = append(recoveryCodeHashes, hash)
+	}
+
+	user.TwoFactor = models.TwoFactor{
+		Enabled:            true,
+		Secret:             user.TwoFactor.PendingSecret,
+		RecoveryCodeHashes: recoveryCodeHashes,
+		LastUsedStep:       step,
+	}
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, twoFactorConfirmResponse{RecoveryCodes: recoveryCodes}, http.StatusOK)
+}
+
+type twoFactorDisablePayload struct {
+	Password string
+	Code     string
+}
+
+// TwoFactorDisable turns off two factor authentication after checking it is the user again and
+// re-checking a second factor
+func (s *Server) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	var payload twoFactorDisablePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if !user.TwoFactor.Enabled {
+		renderError(w, "Two factor authentication is not enabled", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !s.reauthenticate(w, r, payload.Password) {
+		return
+	}
+
+	ok, err := s.checkSecondFactor(user, payload.Code)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		renderError(w, "Incorrect authentication code", http.StatusForbidden)
+		return
+	}
+
+	user.TwoFactor = models.TwoFactor{}
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+// checkSecondFactor accepts either a TOTP code which hasn't been used before or an unused recovery
+// code, which is then consumed
+func (s *Server) checkSecondFactor(user *models.User, code string) (bool, error) {
+	step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now())
+	if ok {
+		if step <= user.TwoFactor.LastUsedStep {
+			return false, nil
+		}
+
+		user.TwoFactor.LastUsedStep = step
+		return true, models.UpdateUser(s.Database, user)
+	}
+
+	codeHash := authservice.HashOpaqueToken(strings.TrimSpace(code))
+	for i, hash := range user.TwoFactor.RecoveryCodeHashes {
+		if hash == codeHash {
+			hashes := user.TwoFactor.RecoveryCodeHashes
+			user.TwoFactor.RecoveryCodeHashes = append(hashes[:i:i], hashes[i+1:]...)
+			return true, models.UpdateUser(s.Database, user)
+		}
+	}
+
+	return false, nil
+}
@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/mailer"
 	"github.com/darkphnx/vehiclemanager/internal/models"
 	"github.com/darkphnx/vehiclemanager/internal/mothistoryapi"
+	"github.com/darkphnx/vehiclemanager/internal/oidc"
+	"github.com/darkphnx/vehiclemanager/internal/registration"
 	"github.com/darkphnx/vehiclemanager/internal/usecases"
 	"github.com/darkphnx/vehiclemanager/internal/vesapi"
 	"github.com/gorilla/mux"
@@ -21,30 +26,36 @@ type Server struct {
 	VehicleEnquiryServiceAPI *vesapi.Client
 	MotHistoryAPI            *mothistoryapi.Client
 	AuthService              *authservice.AuthService
+	Mailer                   *mailer.Mailer
+	BaseURL                  string
+	TrustProxyHeaders        bool
+	Cookies                  CookieSettings
+	OIDCProvider             *oidc.Provider
+	OIDCJustInTime           bool
 }
 
 type vehicleCreatePayload struct {
 	RegistrationNumber string
 }
 
-func (vcp *vehicleCreatePayload) Validate(db *models.Database, user *models.User) []string {
+func (vcp *vehicleCreatePayload) Validate(db *models.Database, scope *vehicleScope) []string {
 	var errors []string
 
-	registrationNumber := strings.ReplaceAll(strings.ToUpper(vcp.RegistrationNumber), " ", "")
-	validRegistration, _ := regexp.MatchString(`^[A-z0-9]{2,7}$`, registrationNumber)
-	if !validRegistration {
-		errors = append(errors, "Registration Number must be valid")
+	reg, err := registration.Parse(vcp.RegistrationNumber)
+	if err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		vcp.RegistrationNumber = reg.Number
 	}
 
-	vehicleExists := models.UserVehicleExists(db, user.ID, registrationNumber)
+	vehicleExists := scope.vehicleExists(db, vcp.RegistrationNumber)
 	if vehicleExists {
-		errors = append(errors, "Vehicle is already added to your account")
+		errors = append(errors, "Vehicle is already added to "+scope.name())
 	}
 
-	vehicleCount := models.UserVehicleCount(db, user.ID)
-	if user.VehicleLimit != 0 && vehicleCount >= user.VehicleLimit {
-		errMsg := fmt.Sprintf("You cannot exceed %d vehicles", user.VehicleLimit)
-		errors = append(errors, errMsg)
+	err = scope.checkVehicleLimit(db)
+	if err != nil {
+		errors = append(errors, err.Error())
 	}
 
 	if len(errors) == 0 {
@@ -64,9 +75,18 @@ func (s *Server) VehicleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := getUserFromContext(r)
+	scope, err := s.getVehicleScope(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !scope.canManage() {
+		renderCannotManageVehicles(w)
+		return
+	}
 
-	validationErrors := payload.Validate(s.Database, user)
+	validationErrors := payload.Validate(s.Database, scope)
 	if validationErrors != nil {
 		renderError(w, validationErrors, http.StatusUnprocessableEntity)
 		return
@@ -82,7 +102,7 @@ func (s *Server) VehicleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vehicle.UserID = user.ID
+	scope.assign(vehicle)
 
 	err = models.CreateVehicle(s.Database, vehicle)
 	if err != nil {
@@ -90,27 +110,124 @@ func (s *Server) VehicleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.audit(r, scope.User.ID, models.AuditVehicleCreated, "vehicle", vehicle.ID.Hex(), vehicle.RegistrationNumber)
+
 	renderJSON(w, vehicle, http.StatusCreated)
 }
 
-// VehicleList returns a list of all vehicles
-func (s *Server) VehicleList(w http.ResponseWriter, r *http.Request) {
-	user := getUserFromContext(r)
+const (
+	defaultVehiclePageSize = 50
+	maxVehiclePageSize     = 100
+)
+
+type vehicleListParams struct {
+	values url.Values
+	query  models.VehicleListQuery
+}
+
+// Validate parses the list parameters into a query. Vehicles are sorted with ?sort= and ?order=desc,
+// filtered with ?mot_due_within= (days), ?tax_expired=true, ?failed_last_mot=true and
+// ?manufacturer=, and paged with ?limit= and ?after=.
+func (vlp *vehicleListParams) Validate() []string {
+	var errors []string
+
+	vlp.query.Sort = vlp.values.Get("sort")
+	if vlp.query.Sort == "" {
+		vlp.query.Sort = models.VehicleSortRegistration
+	} else if !models.ValidVehicleSort(vlp.query.Sort) {
+		errors = append(errors, "Sort must be one of registration, mot_due, ved_due, manufacturer or created")
+	}
 
-	vehicles, err := models.GetUserVehicles(s.Database, user.ID)
+	switch vlp.values.Get("order") {
+	case "", "asc":
+	case "desc":
+		vlp.query.Descending = true
+	default:
+		errors = append(errors, "Order must be asc or desc")
+	}
+
+	vlp.query.Limit = defaultVehiclePageSize
+	if limit := vlp.values.Get("limit"); limit != "" {
+		parsed, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxVehiclePageSize {
+			errors = append(errors, fmt.Sprintf("Limit must be between 1 and %d", maxVehiclePageSize))
+		} else {
+			vlp.query.Limit = parsed
+		}
+	}
+
+	if days := vlp.values.Get("mot_due_within"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			errors = append(errors, "MOT due within must be a number of days")
+		} else {
+			vlp.query.MotDueBefore = time.Now().AddDate(0, 0, parsed)
+		}
+	}
+
+	vlp.query.TaxExpired = vlp.values.Get("tax_expired") == "true"
+	vlp.query.FailedLastMOT = vlp.values.Get("failed_last_mot") == "true"
+	vlp.query.Manufacturer = strings.ToUpper(strings.TrimSpace(vlp.values.Get("manufacturer")))
+	vlp.query.After = vlp.values.Get("after")
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+// VehicleList returns a page of vehicles. When there are more, a Link header points at the next
+// page.
+func (s *Server) VehicleList(w http.ResponseWriter, r *http.Request) {
+	scope, err := s.getVehicleScope(r)
 	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	params := vehicleListParams{values: r.URL.Query()}
+	validationErrors := params.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	page, err := scope.vehiclePage(s.Database, params.query)
+	if err == models.ErrInvalidVehicleCursor {
+		renderError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
 		renderError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	renderJSON(w, vehicles, http.StatusOK)
+	if page.NextCursor != "" {
+		next := *r.URL
+		values := next.Query()
+		values.Set("after", page.NextCursor)
+		next.RawQuery = values.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+
+	if page.Vehicles == nil {
+		page.Vehicles = []*models.Vehicle{}
+	}
+
+	renderJSON(w, page.Vehicles, http.StatusOK)
 }
 
 func (s *Server) VehicleShow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	user := getUserFromContext(r)
 
-	vehicle, err := models.GetUserVehicle(s.Database, user.ID, vars["registration"])
+	scope, err := s.getVehicleScope(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	vehicle, err := scope.vehicle(s.Database, vars["registration"])
 	if err != nil {
 		renderError(w, err.Error(), http.StatusNotFound)
 		return
@@ -119,26 +236,172 @@ func (s *Server) VehicleShow(w http.ResponseWriter, r *http.Request) {
 	renderJSON(w, vehicle, http.StatusOK)
 }
 
-// VehicleDelete deletes a vehicle from the database
+// VehicleDelete deletes a vehicle from the database. For a vehicle shared with the user only their
+// share is removed.
 func (s *Server) VehicleDelete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	user := getUserFromContext(r)
 
-	vehicle, err := models.GetUserVehicle(s.Database, user.ID, vars["registration"])
+	scope, err := s.getVehicleScope(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	vehicle, err := scope.vehicle(s.Database, vars["registration"])
 	if err != nil {
 		renderError(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	err = models.DeleteVehicle(s.Database, vehicle)
+	if scope.Organisation == nil && vehicle.Access != models.VehicleAccessOwner {
+		err = models.DeleteUserVehicleShare(s.Database, vehicle.ID, scope.User.ID)
+		if err != nil {
+			renderError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, scope.User.ID, models.AuditVehicleShareRevoked, "vehicle", vehicle.ID.Hex(), "")
+
+		renderOkay(w, http.StatusOK)
+		return
+	}
+
+	if !scope.canManageVehicle(vehicle) {
+		renderCannotManageVehicles(w)
+		return
+	}
+
+	err = models.PurgeVehicle(s.Database, vehicle)
 	if err != nil {
 		renderError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	s.audit(r, scope.User.ID, models.AuditVehicleDeleted, "vehicle", vehicle.ID.Hex(), vehicle.RegistrationNumber)
+
 	renderOkay(w, http.StatusOK)
 }
 
+type vehicleFollowPayload struct {
+	RegistrationNumber string
+}
+
+// VehicleFollow moves a vehicle whose plate was transferred onto its new registration. The new
+// registration must resolve to the same DVSA vehicle, and previously stored history is kept.
+func (s *Server) VehicleFollow(w http.ResponseWriter, r *http.Request) {
+	var payload vehicleFollowPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	scope, err := s.getVehicleScope(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	vehicle, err := scope.vehicle(s.Database, vars["registration"])
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !scope.canManageVehicle(vehicle) {
+		renderCannotManageVehicles(w)
+		return
+	}
+
+	if vehicle.DVSAVehicleID == "" {
+		renderError(w, "Vehicle identity is unknown so it cannot be followed", http.StatusUnprocessableEntity)
+		return
+	}
+
+	reg, err := registration.Parse(payload.RegistrationNumber)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	registrationNumber := reg.Number
+
+	if scope.vehicleExists(s.Database, registrationNumber) {
+		renderError(w, "Vehicle is already added to "+scope.name(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	vehicleDetails := usecases.VehicleDetails{
+		VehicleEnquiryServiceAPI: s.VehicleEnquiryServiceAPI,
+		MotHistoryAPI:            s.MotHistoryAPI,
+	}
+	details, err := vehicleDetails.Fetch(registrationNumber)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if details.DVSAVehicleID != vehicle.DVSAVehicleID {
+		renderError(w, "Registration Number does not belong to this vehicle", http.StatusUnprocessableEntity)
+		return
+	}
+
+	oldRegistrationNumber := vehicle.RegistrationNumber
+	previousHistory := vehicle.MOTHistory
+
+	vehicle.ApplyDetails(details)
+	vehicle.MOTHistory = usecases.MergeMOTHistory(details.MOTHistory, previousHistory)
+	vehicle.PlateTransferred = false
+
+	err = models.UpdateVehicle(s.Database, vehicle)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	event := models.VehicleEvent{
+		VehicleID:   vehicle.ID,
+		Type:        models.VehicleEventPlateFollowed,
+		Description: fmt.Sprintf("Vehicle moved from registration %s to %s", oldRegistrationNumber, vehicle.RegistrationNumber),
+		OldValue:    oldRegistrationNumber,
+		NewValue:    vehicle.RegistrationNumber,
+	}
+	err = models.CreateVehicleEvent(s.Database, &event)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, vehicle, http.StatusOK)
+}
+
+// VehicleEvents lists the status change events recorded for a vehicle
+func (s *Server) VehicleEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	scope, err := s.getVehicleScope(r)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	vehicle, err := scope.vehicle(s.Database, vars["registration"])
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	events, err := models.GetVehicleEvents(s.Database, vehicle.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(w, events, http.StatusOK)
+}
+
 type simpleResponse struct {
 	Status string
 }
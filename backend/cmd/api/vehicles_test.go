@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+func TestVehicleListParamsValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+		valid bool
+		check func(query models.VehicleListQuery) bool
+	}{
+		{name: "defaults", query: "", valid: true, check: func(q models.VehicleListQuery) bool {
+			return q.Sort == models.VehicleSortRegistration && !q.Descending && q.Limit == defaultVehiclePageSize
+		}},
+		{name: "sort and order", query: "sort=mot_due&order=desc", valid: true, check: func(q models.VehicleListQuery) bool {
+			return q.Sort == models.VehicleSortMotDue && q.Descending
+		}},
+		{name: "unknown sort", query: "sort=colour", valid: false},
+		{name: "unknown order", query: "order=up", valid: false},
+		{name: "limit", query: "limit=10", valid: true, check: func(q models.VehicleListQuery) bool {
+			return q.Limit == 10
+		}},
+		{name: "limit too small", query: "limit=0", valid: false},
+		{name: "limit too large", query: "limit=101", valid: false},
+		{name: "limit not a number", query: "limit=ten", valid: false},
+		{name: "mot due within", query: "mot_due_within=30", valid: true, check: func(q models.VehicleListQuery) bool {
+			return !q.MotDueBefore.IsZero()
+		}},
+		{name: "mot due within negative", query: "mot_due_within=-1", valid: false},
+		{name: "filters", query: "tax_expired=true&failed_last_mot=true&manufacturer=+ford+", valid: true, check: func(q models.VehicleListQuery) bool {
+			return q.TaxExpired && q.FailedLastMOT && q.Manufacturer == "FORD"
+		}},
+		{name: "after", query: "after=abc", valid: true, check: func(q models.VehicleListQuery) bool {
+			return q.After == "abc"
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("Expected no error but got '%s'", err)
+			}
+
+			params := vehicleListParams{values: values}
+			errors := params.Validate()
+			if (errors == nil) != tc.valid {
+				t.Fatalf("Expected valid %t but got errors %v", tc.valid, errors)
+			}
+
+			if tc.check != nil && !tc.check(params.query) {
+				t.Errorf("Expected query to match but got %+v", params.query)
+			}
+		})
+	}
+}
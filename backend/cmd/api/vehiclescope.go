@@ -0,0 +1,156 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// organisationHeaderName selects the organisation whose vehicles a request works with. Without it
+// the user's personal vehicles are used.
+const organisationHeaderName = "X-Organisation-ID"
+
+var (
+	errOrganisationNotFound = errors.New("Organisation not found")
+	errMemberNotFound       = errors.New("Member not found")
+)
+
+// vehicleScope is the set of vehicles a request works with, either the user's personal vehicles
+// or those of an organisation they are a member of
+type vehicleScope struct {
+	User         *models.User
+	Organisation *models.Organisation
+	Membership   *models.Membership
+}
+
+// getVehicleScope chooses the vehicles a request works with from the organisation header
+func (s *Server) getVehicleScope(r *http.Request) (*vehicleScope, error) {
+	user := getUserFromContext(r)
+
+	organisationHeader := r.Header.Get(organisationHeaderName)
+	if organisationHeader == "" {
+		return &vehicleScope{User: user}, nil
+	}
+
+	organisationID, err := primitive.ObjectIDFromHex(organisationHeader)
+	if err != nil {
+		return nil, errOrganisationNotFound
+	}
+
+	membership, err := models.GetMembership(s.Database, organisationID, user.ID)
+	if err != nil {
+		return nil, errOrganisationNotFound
+	}
+
+	organisation, err := models.GetOrganisation(s.Database, organisationID)
+	if err != nil {
+		return nil, errOrganisationNotFound
+	}
+
+	return &vehicleScope{User: user, Organisation: organisation, Membership: membership}, nil
+}
+
+// canManage reports whether vehicles may be added to the scope
+func (vs *vehicleScope) canManage() bool {
+	return vs.Organisation == nil || vs.Membership.CanManageVehicles()
+}
+
+// canManageVehicle reports whether a vehicle loaded from the scope may be changed, from the access
+// set by the user's ownership, share or organisation role
+func (vs *vehicleScope) canManageVehicle(vehicle *models.Vehicle) bool {
+	return vehicle.Access == models.VehicleAccessOwner || vehicle.Access == models.VehicleAccessManage
+}
+
+// canLinkVehicle reports whether public links to a vehicle loaded from the scope may be managed. A
+// link exposes a vehicle further than a share, so for personal vehicles only the owner may, just as
+// only the owner may share them.
+func (vs *vehicleScope) canLinkVehicle(vehicle *models.Vehicle) bool {
+	if vs.Organisation != nil {
+		return vs.Membership.CanManageVehicles()
+	}
+	return vehicle.Access == models.VehicleAccessOwner
+}
+
+// organisationAccess is the access every member with the scope's role has to its vehicles
+func (vs *vehicleScope) organisationAccess() string {
+	if vs.Membership.CanManageVehicles() {
+		return models.VehicleAccessManage
+	}
+	return models.VehicleAccessRead
+}
+
+func (vs *vehicleScope) vehicle(db *models.Database, registrationNumber string) (*models.Vehicle, error) {
+	if vs.Organisation != nil {
+		vehicle, err := models.GetOrganisationVehicle(db, vs.Organisation.ID, registrationNumber)
+		if err != nil {
+			return nil, err
+		}
+		vehicle.Access = vs.organisationAccess()
+		return vehicle, nil
+	}
+	return models.GetUserVehicle(db, vs.User.ID, registrationNumber)
+}
+
+func (vs *vehicleScope) vehiclePage(db *models.Database, query models.VehicleListQuery) (*models.VehiclePage, error) {
+	if vs.Organisation != nil {
+		page, err := models.GetOrganisationVehiclePage(db, vs.Organisation.ID, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, vehicle := range page.Vehicles {
+			vehicle.Access = vs.organisationAccess()
+		}
+		return page, nil
+	}
+	return models.GetUserVehiclePage(db, vs.User.ID, query)
+}
+
+func (vs *vehicleScope) vehicleExists(db *models.Database, registrationNumber string) bool {
+	if vs.Organisation != nil {
+		return models.OrganisationVehicleExists(db, vs.Organisation.ID, registrationNumber)
+	}
+	return models.UserVehicleExists(db, vs.User.ID, registrationNumber)
+}
+
+func (vs *vehicleScope) vehicleCount(db *models.Database) int64 {
+	if vs.Organisation != nil {
+		return models.OrganisationVehicleCount(db, vs.Organisation.ID)
+	}
+	return models.UserVehicleCount(db, vs.User.ID)
+}
+
+// plan returns the plan limiting the scope's vehicles: the organisation's, or the user's own
+func (vs *vehicleScope) plan() *plans.Plan {
+	if vs.Organisation != nil {
+		return plans.Get(vs.Organisation.Plan)
+	}
+	return plans.Get(vs.User.Plan)
+}
+
+// checkVehicleLimit returns an error if the scope's plan doesn't allow another vehicle
+func (vs *vehicleScope) checkVehicleLimit(db *models.Database) error {
+	return vs.plan().CheckVehicleCount(vs.vehicleCount(db))
+}
+
+// assign makes a new vehicle belong to the scope. The user who added it is always recorded.
+func (vs *vehicleScope) assign(vehicle *models.Vehicle) {
+	vehicle.UserID = vs.User.ID
+	if vs.Organisation != nil {
+		vehicle.OrganisationID = vs.Organisation.ID
+	}
+}
+
+// name describes the scope in messages, e.g. "Vehicle is already added to your account"
+func (vs *vehicleScope) name() string {
+	if vs.Organisation != nil {
+		return vs.Organisation.Name
+	}
+	return "your account"
+}
+
+func renderCannotManageVehicles(w http.ResponseWriter) {
+	renderError(w, "You do not have permission to manage this vehicle", http.StatusForbidden)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type vehicleShareCreatePayload struct {
+	Email  string
+	Access string
+}
+
+func (vscp *vehicleShareCreatePayload) Validate() []string {
+	var errors []string
+
+	if vscp.Email == "" {
+		errors = append(errors, "E-mail address must be given")
+	}
+
+	if vscp.Access != models.VehicleAccessRead && vscp.Access != models.VehicleAccessManage {
+		errors = append(errors, "Access must be read or manage")
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return errors
+	}
+}
+
+type vehicleShareResponse struct {
+	*models.VehicleShare
+	Email string
+}
+
+// VehicleShareList lists the users a vehicle is shared with
+func (s *Server) VehicleShareList(w http.ResponseWriter, r *http.Request) {
+	vehicle, ok := s.getOwnedVehicle(w, r)
+	if !ok {
+		return
+	}
+
+	shares, err := models.GetVehicleShares(s.Database, vehicle.ID)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := []vehicleShareResponse{}
+	for _, share := range shares {
+		email := share.Email
+
+		// Shares made before they were addressed by e-mail only know their user
+		if email == "" {
+			user, err := models.GetUserByID(s.Database, share.UserID)
+			if err != nil {
+				continue
+			}
+			email = user.Email
+		}
+
+		response = append(response, vehicleShareResponse{VehicleShare: share, Email: email})
+	}
+
+	renderJSON(w, response, http.StatusOK)
+}
+
+// VehicleShareCreate shares a vehicle with an e-mail address, or changes the access it already has.
+// The response is the same whether or not the address is registered, so it can't be used to find
+// out. Shares to an unregistered address take effect once someone verifies it on an account.
+func (s *Server) VehicleShareCreate(w http.ResponseWriter, r *http.Request) {
+	var payload vehicleShareCreatePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vehicle, ok := s.getOwnedVehicle(w, r)
+	if !ok {
+		return
+	}
+
+	validationErrors := payload.Validate()
+	if validationErrors != nil {
+		renderError(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	owner := getUserFromContext(r)
+	if payload.Email == owner.Email {
+		renderError(w, "You cannot share a vehicle with yourself", http.StatusUnprocessableEntity)
+		return
+	}
+
+	share := models.VehicleShare{
+		VehicleID: vehicle.ID,
+		OwnerID:   vehicle.UserID,
+		Email:     payload.Email,
+		Access:    payload.Access,
+	}
+
+	user, err := models.GetUser(s.Database, payload.Email)
+	if err == nil && !user.EmailUnverified {
+		share.UserID = user.ID
+	}
+
+	err = models.SaveVehicleShare(s.Database, &share)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.audit(r, vehicle.UserID, models.AuditVehicleShared, "vehicle", vehicle.ID.Hex(), share.Email+" "+share.Access)
+
+	body := fmt.Sprintf("%s has shared the vehicle %s with you on MOT.ninja. Sign in, or sign up with this e-mail address, to see it:\n\n%s\n", owner.Email, vehicle.RegistrationNumber, s.BaseURL)
+	err = s.Mailer.Send(share.Email, fmt.Sprintf("%s has been shared with you", vehicle.RegistrationNumber), body)
+	if err != nil {
+		log.Println(err)
+	}
+
+	renderJSON(w, vehicleShareResponse{VehicleShare: &share, Email: share.Email}, http.StatusCreated)
+}
+
+// VehicleShareDelete revokes a user's access to a vehicle
+func (s *Server) VehicleShareDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	vehicle, ok := s.getOwnedVehicle(w, r)
+	if !ok {
+		return
+	}
+
+	shareID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		renderError(w, "Share not found", http.StatusNotFound)
+		return
+	}
+
+	err = models.DeleteVehicleShare(s.Database, vehicle.ID, shareID)
+	if err != nil {
+		renderError(w, "Share not found", http.StatusNotFound)
+		return
+	}
+
+	s.audit(r, vehicle.UserID, models.AuditVehicleShareRevoked, "vehicle", vehicle.ID.Hex(), shareID.Hex())
+
+	renderOkay(w, http.StatusOK)
+}
+
+// claimVehicleShares gives a user any vehicles shared with their e-mail address before it was
+// verified. Failing to do so doesn't stop them signing in, as the shares can be claimed later.
+func (s *Server) claimVehicleShares(user *models.User) {
+	err := models.ClaimVehicleShares(s.Database, user.Email, user.ID)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// getOwnedVehicle loads the personal vehicle named in the route, rendering an error unless the
+// current user owns it. Only owners may see and change who a vehicle is shared with.
+func (s *Server) getOwnedVehicle(w http.ResponseWriter, r *http.Request) (*models.Vehicle, bool) {
+	vars := mux.Vars(r)
+	user := getUserFromContext(r)
+
+	vehicle, err := models.GetUserVehicle(s.Database, user.ID, vars["registration"])
+	if err != nil {
+		renderError(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+
+	if vehicle.Access != models.VehicleAccessOwner {
+		renderError(w, "Only the owner can share this vehicle", http.StatusForbidden)
+		return nil, false
+	}
+
+	return vehicle, true
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// verificationResendInterval is the minimum time between verification e-mails for one user
+const verificationResendInterval = 2 * time.Minute
+
+type verifyEmailPayload struct {
+	Token string
+}
+
+// VerifyEmail activates the account named in a verification token, or completes a change of
+// e-mail address if the token is for the user's pending address
+func (s *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload verifyEmailPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claim, err := s.AuthService.VerifyEmailVerificationToken(payload.Token)
+	if err != nil {
+		renderError(w, "Verification link is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claim.Subject)
+	if err != nil {
+		renderError(w, "Verification link is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	user, err := models.GetUserByID(s.Database, userID)
+	if err != nil {
+		renderError(w, "Verification link is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	switch claim.Email {
+	case user.Email:
+		user.EmailUnverified = false
+	case user.PendingEmail:
+		if models.UserExists(s.Database, user.PendingEmail) {
+			renderError(w, "E-mail address is already registered", http.StatusUnprocessableEntity)
+			return
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+		user.EmailUnverified = false
+	default:
+		renderError(w, "Verification link is invalid or has expired", http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.claimVehicleShares(user)
+
+	renderOkay(w, http.StatusOK)
+}
+
+type resendVerificationPayload struct {
+	Email string
+}
+
+// ResendVerificationEmail sends a fresh verification link, no more than once per interval. The
+// response is the same whether or not a mail was sent, so it doesn't reveal which addresses are
+// registered.
+func (s *Server) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	var payload resendVerificationPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		renderError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := models.GetUser(s.Database, payload.Email)
+	if err == nil && user.EmailUnverified && time.Since(user.VerificationSentAt) >= verificationResendInterval {
+		err = s.sendVerificationEmail(user, user.Email)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	renderOkay(w, http.StatusOK)
+}
+
+func (s *Server) sendVerificationEmail(user *models.User, email string) error {
+	token, err := s.AuthService.GenerateEmailVerificationToken(user, email)
+	if err != nil {
+		return err
+	}
+
+	user.VerificationSentAt = time.Now()
+	err = models.UpdateUser(s.Database, user)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.BaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Please confirm your MOT.ninja e-mail address by visiting the link below:\n\n%s\n\nThis link expires in 48 hours.\n", link)
+
+	return s.Mailer.Send(email, "Confirm your e-mail address", body)
+}
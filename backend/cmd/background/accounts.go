@@ -0,0 +1,193 @@
+package background
+
+import (
+	"log"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeleteAccountsJob is the name the scheduled account deletion job's status is recorded under
+const DeleteAccountsJob = "delete_accounts"
+
+func (bt *Task) deleteAccounts() {
+	users, err := models.GetUsersDueForDeletion(bt.Database, time.Now())
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if len(users) == 0 {
+		return
+	}
+
+	log.Println("Delete Accounts")
+
+	run := models.JobRun{Name: DeleteAccountsJob, Running: true, StartedAt: time.Now()}
+	bt.saveJobRun(&run)
+
+	defer func() {
+		run.Running = false
+		run.FinishedAt = time.Now()
+		bt.saveJobRun(&run)
+	}()
+
+	for _, user := range users {
+		log.Printf("Deleting account %s...\n", user.ID.Hex())
+
+		run.Processed++
+
+		err = bt.deleteAccount(user)
+		if err != nil {
+			log.Println(err)
+			run.Failed++
+			run.LastError = err.Error()
+		}
+	}
+
+	log.Println("Deleting Accounts Complete")
+}
+
+// deleteAccount removes a user along with everything that belongs only to them. The audit log is
+// kept, and records the deletion.
+func (bt *Task) deleteAccount(user *models.User) error {
+	vehicles, err := models.GetUserVehicles(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, vehicle := range vehicles {
+		if vehicle.Access != models.VehicleAccessOwner {
+			continue
+		}
+
+		err = models.PurgeVehicle(bt.Database, vehicle)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = models.DeleteUserVehicleShares(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	memberships, err := models.GetUserMemberships(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		err = bt.leaveOrganisation(membership)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = models.DeleteUserAPITokens(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = models.DeleteUserRefreshTokens(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = models.DeleteUserSessions(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = models.DeleteUserPasswordResets(bt.Database, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = models.DeleteUser(bt.Database, user)
+	if err != nil {
+		return err
+	}
+
+	entry := models.AuditEntry{
+		Action:     models.AuditAccountDeleted,
+		TargetType: "user",
+		TargetID:   user.ID.Hex(),
+	}
+
+	return models.CreateAuditEntry(bt.Database, &entry)
+}
+
+// leaveOrganisation removes a deleted user's membership, deleting or handing over the organisation
+// as organisationSuccession decides
+func (bt *Task) leaveOrganisation(membership *models.Membership) error {
+	members, err := models.GetOrganisationMemberships(bt.Database, membership.OrganisationID)
+	if err != nil {
+		return err
+	}
+
+	deleteOrganisation, successor := organisationSuccession(members, membership)
+	if deleteOrganisation {
+		return bt.deleteOrganisation(membership.OrganisationID)
+	}
+
+	err = models.DeleteMembership(bt.Database, membership)
+	if err != nil {
+		return err
+	}
+
+	if successor == nil {
+		return nil
+	}
+
+	successor.Role = models.RoleOwner
+
+	return models.UpdateMembership(bt.Database, successor)
+}
+
+// organisationSuccession decides what happens to an organisation when a member leaves. One left
+// without an owner has a remaining admin promoted, or failing that a member. Viewers are never
+// promoted, so one left with only viewers, or with nobody, is deleted with its vehicles. Otherwise
+// the successor is nil.
+func organisationSuccession(members []*models.Membership, leaving *models.Membership) (bool, *models.Membership) {
+	var remaining []*models.Membership
+	for _, member := range members {
+		if member.ID == leaving.ID {
+			continue
+		}
+
+		if member.Role == models.RoleOwner && leaving.Role == models.RoleOwner {
+			return false, nil
+		}
+
+		remaining = append(remaining, member)
+	}
+
+	if len(remaining) == 0 {
+		return true, nil
+	}
+
+	if leaving.Role != models.RoleOwner {
+		return false, nil
+	}
+
+	for _, role := range []string{models.RoleAdmin, models.RoleMember} {
+		for _, member := range remaining {
+			if member.Role == role {
+				return false, member
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func (bt *Task) deleteOrganisation(organisationID primitive.ObjectID) error {
+	organisation, err := models.GetOrganisation(bt.Database, organisationID)
+	if err != nil {
+		return err
+	}
+
+	return models.PurgeOrganisation(bt.Database, organisation)
+}
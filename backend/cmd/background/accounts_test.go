@@ -0,0 +1,46 @@
+package background
+
+import (
+	"testing"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOrganisationSuccession(t *testing.T) {
+	leavingOwner := &models.Membership{ID: primitive.NewObjectID(), Role: models.RoleOwner}
+	leavingMember := &models.Membership{ID: primitive.NewObjectID(), Role: models.RoleMember}
+	otherOwner := &models.Membership{ID: primitive.NewObjectID(), Role: models.RoleOwner}
+	admin := &models.Membership{ID: primitive.NewObjectID(), Role: models.RoleAdmin}
+	member := &models.Membership{ID: primitive.NewObjectID(), Role: models.RoleMember}
+	viewer := &models.Membership{ID: primitive.NewObjectID(), Role: models.RoleViewer}
+
+	testCases := []struct {
+		name      string
+		members   []*models.Membership
+		leaving   *models.Membership
+		delete    bool
+		successor *models.Membership
+	}{
+		{name: "only member leaves", members: []*models.Membership{leavingOwner}, leaving: leavingOwner, delete: true},
+		{name: "owner leaves another owner", members: []*models.Membership{leavingOwner, otherOwner, admin}, leaving: leavingOwner},
+		{name: "owner leaves an admin", members: []*models.Membership{leavingOwner, member, admin, viewer}, leaving: leavingOwner, successor: admin},
+		{name: "owner leaves no admin", members: []*models.Membership{leavingOwner, viewer, member}, leaving: leavingOwner, successor: member},
+		{name: "owner leaves only viewers", members: []*models.Membership{leavingOwner, viewer}, leaving: leavingOwner, delete: true},
+		{name: "member leaves", members: []*models.Membership{otherOwner, leavingMember}, leaving: leavingMember},
+		{name: "member leaves no owner", members: []*models.Membership{leavingMember, viewer}, leaving: leavingMember},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deleteOrganisation, successor := organisationSuccession(tc.members, tc.leaving)
+			if deleteOrganisation != tc.delete {
+				t.Errorf("Expected delete %t but got %t", tc.delete, deleteOrganisation)
+			}
+
+			if successor != tc.successor {
+				t.Errorf("Expected successor %+v but got %+v", tc.successor, successor)
+			}
+		})
+	}
+}
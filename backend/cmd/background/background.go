@@ -1,13 +1,17 @@
 package background
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/darkphnx/vehiclemanager/internal/models"
 	"github.com/darkphnx/vehiclemanager/internal/mothistoryapi"
+	"github.com/darkphnx/vehiclemanager/internal/notifier"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
 	"github.com/darkphnx/vehiclemanager/internal/usecases"
 	"github.com/darkphnx/vehiclemanager/internal/vesapi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Task contains all of the external connections we need
@@ -15,9 +19,10 @@ type Task struct {
 	Database                 *models.Database
 	VehicleEnquiryServiceAPI *vesapi.Client
 	MotHistoryAPI            *mothistoryapi.Client
+	Notifier                 *notifier.Notifier
 }
 
-// Begin fetches new MOT data every 5 minutes
+// Begin fetches new MOT data and deletes accounts due for deletion every minute
 func (bt *Task) Begin() {
 	ticker := time.NewTicker(1 * time.Minute)
 
@@ -25,17 +30,31 @@ func (bt *Task) Begin() {
 		select {
 		case <-ticker.C:
 			bt.updateVehicles()
+			bt.deleteAccounts()
 		}
 	}
 }
 
+// UpdateVehiclesJob is the name the vehicle refresh job's status is recorded under
+const UpdateVehiclesJob = "update_vehicles"
+
 func (bt *Task) updateVehicles() {
 	log.Println("Update Vehicles")
 
-	timestamp := time.Now().Add(-1 * time.Hour)
-	vehicles, err := models.GetVehiclesUpdatedBefore(bt.Database, timestamp)
+	run := models.JobRun{Name: UpdateVehiclesJob, Running: true, StartedAt: time.Now()}
+	bt.saveJobRun(&run)
+
+	defer func() {
+		run.Running = false
+		run.FinishedAt = time.Now()
+		bt.saveJobRun(&run)
+	}()
+
+	now := time.Now()
+	vehicles, err := models.GetVehiclesUpdatedBefore(bt.Database, now.Add(-plans.MinRefreshInterval()))
 	if err != nil {
 		log.Println(err)
+		run.LastError = err.Error()
 	}
 
 	vehicleDetails := usecases.VehicleDetails{
@@ -43,24 +62,179 @@ func (bt *Task) updateVehicles() {
 		MotHistoryAPI:            bt.MotHistoryAPI,
 	}
 
+	ownerPlans := make(map[primitive.ObjectID]*plans.Plan)
+	organisationPlans := make(map[primitive.ObjectID]*plans.Plan)
+
 	for _, vehicle := range vehicles {
+		if !bt.vehiclePlan(vehicle, ownerPlans, organisationPlans).RefreshDue(vehicle.LastFetchedAt, now) {
+			continue
+		}
+
 		log.Printf("Updating vehicle %s...\n", vehicle.RegistrationNumber)
 
+		run.Processed++
+
 		updatedVehicleDetails, err := vehicleDetails.Fetch(vehicle.RegistrationNumber)
 		if err != nil {
 			log.Println(err)
+			run.Failed++
+			run.LastError = err.Error()
+			continue
 		}
 
-		vehicle.MOTHistory = updatedVehicleDetails.MOTHistory
-		vehicle.MotDue = updatedVehicleDetails.MotDue
-		vehicle.VEDDue = updatedVehicleDetails.VEDDue
-		vehicle.LastFetchedAt = updatedVehicleDetails.LastFetchedAt
+		var events []models.VehicleEvent
+
+		transferEvent := usecases.DetectPlateTransfer(vehicle, updatedVehicleDetails)
+		if transferEvent != nil {
+			// The registration now belongs to another vehicle, keep the history we have rather
+			// than mixing in someone else's until the user decides what to do
+			if !vehicle.PlateTransferred {
+				vehicle.PlateTransferred = true
+				events = append(events, *transferEvent)
+			}
+			vehicle.LastFetchedAt = updatedVehicleDetails.LastFetchedAt
+		} else {
+			// The registration belongs to the same vehicle again, so any earlier transfer no
+			// longer applies
+			vehicle.PlateTransferred = false
+			events = usecases.DetectStatusChanges(vehicle, updatedVehicleDetails)
+			vehicle.ApplyDetails(updatedVehicleDetails)
+		}
 
+		// Changes are only announced once saved, otherwise the next run would find and announce
+		// them again
 		err = models.UpdateVehicle(bt.Database, vehicle)
 		if err != nil {
 			log.Println(err)
+			run.Failed++
+			run.LastError = err.Error()
+			continue
 		}
+
+		bt.recordEvents(vehicle, events)
 	}
 
 	log.Println("Updating Vehicles Complete")
 }
+
+// vehiclePlan returns the plan deciding how often a vehicle is refreshed: its organisation's plan,
+// or its owner's for a personal vehicle. Plans are looked up once per run through the given maps.
+func (bt *Task) vehiclePlan(vehicle *models.Vehicle, ownerPlans, organisationPlans map[primitive.ObjectID]*plans.Plan) *plans.Plan {
+	if !vehicle.OrganisationID.IsZero() {
+		plan, ok := organisationPlans[vehicle.OrganisationID]
+		if !ok {
+			var planName string
+
+			organisation, err := models.GetOrganisation(bt.Database, vehicle.OrganisationID)
+			if err != nil {
+				log.Println(err)
+			} else {
+				planName = organisation.Plan
+			}
+
+			plan = plans.Get(planName)
+			organisationPlans[vehicle.OrganisationID] = plan
+		}
+
+		return plan
+	}
+
+	plan, ok := ownerPlans[vehicle.UserID]
+	if !ok {
+		var planName string
+
+		owner, err := models.GetUserByID(bt.Database, vehicle.UserID)
+		if err != nil {
+			log.Println(err)
+		} else {
+			planName = owner.Plan
+		}
+
+		plan = plans.Get(planName)
+		ownerPlans[vehicle.UserID] = plan
+	}
+
+	return plan
+}
+
+func (bt *Task) saveJobRun(run *models.JobRun) {
+	err := models.SaveJobRun(bt.Database, run)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bt *Task) recordEvents(vehicle *models.Vehicle, events []models.VehicleEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	recipients, err := bt.vehicleRecipients(vehicle)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, event := range events {
+		err = models.CreateVehicleEvent(bt.Database, &event)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		notification := notifier.Notification{
+			Subject: fmt.Sprintf("Status change for %s", vehicle.RegistrationNumber),
+			Body:    event.Description,
+		}
+
+		for _, user := range recipients {
+			err = bt.Notifier.Notify(user, notification)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// vehicleRecipients returns the users who should hear about changes to a vehicle: its owner and
+// anyone it is shared with, or every member of the organisation it belongs to. Each user's own
+// notification settings decide whether they are actually told.
+func (bt *Task) vehicleRecipients(vehicle *models.Vehicle) ([]*models.User, error) {
+	var userIDs []primitive.ObjectID
+
+	if vehicle.OrganisationID.IsZero() {
+		shares, err := models.GetVehicleShares(bt.Database, vehicle.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		userIDs = append(userIDs, vehicle.UserID)
+		for _, share := range shares {
+			if !share.UserID.IsZero() {
+				userIDs = append(userIDs, share.UserID)
+			}
+		}
+	} else {
+		memberships, err := models.GetOrganisationMemberships(bt.Database, vehicle.OrganisationID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, membership := range memberships {
+			userIDs = append(userIDs, membership.UserID)
+		}
+	}
+
+	var users []*models.User
+	for _, userID := range userIDs {
+		user, err := models.GetUserByID(bt.Database, userID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
@@ -6,14 +6,18 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/darkphnx/vehiclemanager/cmd/api"
 	"github.com/darkphnx/vehiclemanager/cmd/background"
 	"github.com/darkphnx/vehiclemanager/internal/authservice"
+	"github.com/darkphnx/vehiclemanager/internal/mailer"
 	"github.com/darkphnx/vehiclemanager/internal/models"
 	"github.com/darkphnx/vehiclemanager/internal/mothistoryapi"
+	"github.com/darkphnx/vehiclemanager/internal/notifier"
+	"github.com/darkphnx/vehiclemanager/internal/oidc"
 	"github.com/darkphnx/vehiclemanager/internal/vesapi"
 )
 
@@ -60,11 +64,40 @@ func (h spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	http.FileServer(http.Dir(h.staticPath)).ServeHTTP(w, r)
 }
 
+// loadJwtKeys reads the keyset if one is configured, otherwise the single signing secret is used
+func loadJwtKeys(keysetPath, signingSecret string) (*authservice.KeySet, error) {
+	if keysetPath != "" {
+		return authservice.LoadKeySet(keysetPath)
+	}
+
+	keys := authservice.NewKeySet()
+	err := keys.Add(authservice.NewHMACKey("default", signingSecret), true)
+
+	return keys, err
+}
+
 func main() {
 	vesapiKey := flag.String("vesapi-key", "", "Vehicle Enquiry Service API Key")
 	mothistoryapiKey := flag.String("mothistoryapi-key", "", "MOT History API Key")
-	jwtSigningSecret := flag.String("jwt-signing-secret", "", "JWT Signing Secret")
+	jwtSigningSecret := flag.String("jwt-signing-secret", "", "JWT Signing Secret, used when no keyset is given")
+	jwtKeyset := flag.String("jwt-keyset", "", "Path to a JSON JWT keyset configuration")
 	mongoConnectionString := flag.String("mongo-connection-string", "", "MongoDB Connection String")
+	smtpHost := flag.String("smtp-host", "", "SMTP Host, mail is logged rather than sent if blank")
+	smtpPort := flag.Int("smtp-port", 587, "SMTP Port")
+	smtpUsername := flag.String("smtp-username", "", "SMTP Username")
+	smtpPassword := flag.String("smtp-password", "", "SMTP Password")
+	mailFrom := flag.String("mail-from", "noreply@mot.ninja", "Address mail is sent from")
+	trustProxyHeaders := flag.Bool("trust-proxy-headers", false, "Use X-Forwarded-For to determine client IP addresses")
+	baseURL := flag.String("base-url", "http://localhost:4000", "Public URL used in links sent by e-mail")
+	cookieSecure := flag.Bool("cookie-secure", false, "Only send authentication cookies over HTTPS")
+	cookieSameSite := flag.String("cookie-samesite", "lax", "SameSite mode for authentication cookies: lax, strict or none")
+	cookiePath := flag.String("cookie-path", "/", "Path authentication cookies are scoped to")
+	oidcIssuer := flag.String("oidc-issuer", "", "OpenID Connect issuer URL, single sign-on is disabled if blank")
+	oidcClientID := flag.String("oidc-client-id", "", "OpenID Connect client ID")
+	oidcClientSecret := flag.String("oidc-client-secret", "", "OpenID Connect client secret")
+	oidcJustInTime := flag.Bool("oidc-jit", false, "Create accounts for unknown users signing in with OpenID Connect")
+	grantAdmin := flag.String("grant-admin", "", "Make the account with this e-mail address an administrator and exit")
+	unlockAccount := flag.String("unlock-account", "", "Lift the login lockout on the account with this e-mail address and exit")
 	flag.Parse()
 
 	database, err := models.InitDB(*mongoConnectionString)
@@ -72,14 +105,48 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *grantAdmin != "" {
+		err = api.GrantAdmin(database, *grantAdmin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Granted administrator access to %s\n", *grantAdmin)
+		return
+	}
+
+	if *unlockAccount != "" {
+		err = api.UnlockAccount(database, *unlockAccount)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Unlocked %s\n", *unlockAccount)
+		return
+	}
+
 	vesapiClient := vesapi.NewClient(*vesapiKey, "")
 	mothistoryClient := mothistoryapi.NewClient(*mothistoryapiKey, "")
-	authService := authservice.NewAuthService(*jwtSigningSecret, 24, "mot.ninja")
+	jwtKeys, err := loadJwtKeys(*jwtKeyset, *jwtSigningSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sameSite, err := api.ParseSameSite(*cookieSameSite)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if sameSite == http.SameSiteNoneMode && !*cookieSecure {
+		log.Fatal("SameSite none cookies must also be secure")
+	}
+
+	authService := authservice.NewAuthService(jwtKeys, 15*time.Minute, 30*24*time.Hour, "mot.ninja")
+	mailerClient := mailer.NewMailer(*smtpHost, *smtpPort, *smtpUsername, *smtpPassword, *mailFrom)
+	notifierClient := notifier.NewNotifier(mailerClient)
 
 	backgroundTasks := background.Task{
 		Database:                 database,
 		VehicleEnquiryServiceAPI: vesapiClient,
 		MotHistoryAPI:            mothistoryClient,
+		Notifier:                 notifierClient,
 	}
 	go backgroundTasks.Begin()
 
@@ -88,6 +155,19 @@ func main() {
 		VehicleEnquiryServiceAPI: vesapiClient,
 		MotHistoryAPI:            mothistoryClient,
 		AuthService:              authService,
+		Mailer:                   mailerClient,
+		BaseURL:                  *baseURL,
+		TrustProxyHeaders:        *trustProxyHeaders,
+		Cookies: api.CookieSettings{
+			Secure:   *cookieSecure,
+			SameSite: sameSite,
+			Path:     *cookiePath,
+		},
+		OIDCJustInTime: *oidcJustInTime,
+	}
+
+	if *oidcIssuer != "" {
+		apiServer.OIDCProvider = oidc.NewProvider(*oidcIssuer, *oidcClientID, *oidcClientSecret, *baseURL+"/oidc/callback")
 	}
 
 	mux := mux.NewRouter()
@@ -96,14 +176,81 @@ func main() {
 
 	mux.HandleFunc("/signup", apiServer.Signup).Methods("POST")
 	mux.HandleFunc("/login", apiServer.Login).Methods("POST")
-	mux.HandleFunc("/logout", apiServer.Logout).Methods("GET")
+	mux.HandleFunc("/login/2fa", apiServer.LoginTwoFactor).Methods("POST")
+	mux.HandleFunc("/logout", apiServer.Logout).Methods("POST")
+	mux.HandleFunc("/token/refresh", apiServer.TokenRefresh).Methods("POST")
+	mux.HandleFunc("/.well-known/jwks.json", apiServer.JWKS).Methods("GET")
+	mux.HandleFunc("/verify-email", apiServer.VerifyEmail).Methods("POST")
+	mux.HandleFunc("/verify-email/resend", apiServer.ResendVerificationEmail).Methods("POST")
+	mux.HandleFunc("/password/forgot", apiServer.PasswordForgot).Methods("POST")
+	mux.HandleFunc("/password/reset", apiServer.PasswordReset).Methods("POST")
+	mux.HandleFunc("/lookup/{registration}", apiServer.Lookup).Methods("GET")
+	mux.HandleFunc("/share/{token}", apiServer.SharedVehiclePage).Methods("GET")
+	mux.HandleFunc("/share/{token}/vehicle", apiServer.SharedVehicle).Methods("GET")
+
+	if apiServer.OIDCProvider != nil {
+		mux.HandleFunc("/oidc/login", apiServer.OIDCLogin).Methods("GET")
+		mux.HandleFunc("/oidc/callback", apiServer.OIDCCallback).Methods("GET")
+	}
 
 	apiMux := mux.PathPrefix("/api").Subrouter()
 	apiMux.Use(apiServer.AuthJwtTokenMiddleware)
+	apiMux.Use(api.RegistrationMiddleware)
 	apiMux.HandleFunc("/vehicles/{registration}", apiServer.VehicleShow).Methods("GET")
+	apiMux.HandleFunc("/vehicles/{registration}/events", apiServer.VehicleEvents).Methods("GET")
+	apiMux.HandleFunc("/vehicles/{registration}/follow", apiServer.VehicleFollow).Methods("POST")
 	apiMux.HandleFunc("/vehicles/{registration}", apiServer.VehicleDelete).Methods("DELETE")
+	apiMux.HandleFunc("/vehicles/{registration}/shares", apiServer.VehicleShareList).Methods("GET")
+	apiMux.HandleFunc("/vehicles/{registration}/shares", apiServer.VehicleShareCreate).Methods("POST")
+	apiMux.HandleFunc("/vehicles/{registration}/shares/{id}", apiServer.VehicleShareDelete).Methods("DELETE")
+	apiMux.HandleFunc("/vehicles/{registration}/links", apiServer.ShareLinkList).Methods("GET")
+	apiMux.HandleFunc("/vehicles/{registration}/links", apiServer.ShareLinkCreate).Methods("POST")
+	apiMux.HandleFunc("/vehicles/{registration}/links/{id}", apiServer.ShareLinkDelete).Methods("DELETE")
 	apiMux.HandleFunc("/vehicles", apiServer.VehicleList).Methods("GET")
 	apiMux.HandleFunc("/vehicles", apiServer.VehicleCreate).Methods("POST")
+	apiMux.HandleFunc("/notifications", apiServer.NotificationSettingsShow).Methods("GET")
+	apiMux.HandleFunc("/notifications", apiServer.NotificationSettingsUpdate).Methods("PUT")
+	apiMux.HandleFunc("/account", apiServer.AccountShow).Methods("GET")
+	apiMux.HandleFunc("/account/email", apiServer.AccountEmailChange).Methods("POST")
+	apiMux.HandleFunc("/account/plan", apiServer.AccountPlan).Methods("GET")
+	apiMux.HandleFunc("/account/audit", apiServer.AccountAuditList).Methods("GET")
+	apiMux.HandleFunc("/account/export", apiServer.AccountExport).Methods("GET")
+	apiMux.HandleFunc("/account/deletion", apiServer.AccountDeletionSchedule).Methods("POST")
+	apiMux.HandleFunc("/account/deletion", apiServer.AccountDeletionCancel).Methods("DELETE")
+	apiMux.HandleFunc("/account/2fa/enrol", apiServer.TwoFactorEnrol).Methods("POST")
+	apiMux.HandleFunc("/account/2fa/confirm", apiServer.TwoFactorConfirm).Methods("POST")
+	apiMux.HandleFunc("/account/2fa/disable", apiServer.TwoFactorDisable).Methods("POST")
+	apiMux.HandleFunc("/organisations", apiServer.OrganisationList).Methods("GET")
+	apiMux.HandleFunc("/organisations", apiServer.OrganisationCreate).Methods("POST")
+	apiMux.HandleFunc("/organisations/{organisation}", apiServer.OrganisationShow).Methods("GET")
+	apiMux.HandleFunc("/organisations/{organisation}", apiServer.OrganisationUpdate).Methods("PUT")
+	apiMux.HandleFunc("/organisations/{organisation}", apiServer.OrganisationDelete).Methods("DELETE")
+	apiMux.HandleFunc("/organisations/{organisation}/members", apiServer.OrganisationMemberList).Methods("GET")
+	apiMux.HandleFunc("/organisations/{organisation}/members/{user}", apiServer.OrganisationMemberUpdate).Methods("PUT")
+	apiMux.HandleFunc("/organisations/{organisation}/members/{user}", apiServer.OrganisationMemberRemove).Methods("DELETE")
+	apiMux.HandleFunc("/organisations/{organisation}/invitations", apiServer.InvitationList).Methods("GET")
+	apiMux.HandleFunc("/organisations/{organisation}/invitations", apiServer.InvitationCreate).Methods("POST")
+	apiMux.HandleFunc("/organisations/{organisation}/invitations/{id}", apiServer.InvitationDelete).Methods("DELETE")
+	apiMux.HandleFunc("/invitations/accept", apiServer.InvitationAccept).Methods("POST")
+	apiMux.HandleFunc("/tokens", apiServer.APITokenList).Methods("GET")
+	apiMux.HandleFunc("/tokens", apiServer.APITokenCreate).Methods("POST")
+	apiMux.HandleFunc("/tokens/{id}", apiServer.APITokenDelete).Methods("DELETE")
+	apiMux.HandleFunc("/sessions", apiServer.SessionList).Methods("GET")
+	apiMux.HandleFunc("/sessions", apiServer.SessionRevokeAll).Methods("DELETE")
+	apiMux.HandleFunc("/sessions/{id}", apiServer.SessionRevoke).Methods("DELETE")
+
+	adminMux := apiMux.PathPrefix("/admin").Subrouter()
+	adminMux.Use(api.AdminMiddleware)
+	adminMux.HandleFunc("/users", apiServer.AdminUserList).Methods("GET")
+	adminMux.HandleFunc("/users/{id}", apiServer.AdminUserShow).Methods("GET")
+	adminMux.HandleFunc("/users/{id}/plan", apiServer.AdminUserPlan).Methods("PUT")
+	adminMux.HandleFunc("/users/{id}/disable", apiServer.AdminUserDisable).Methods("POST")
+	adminMux.HandleFunc("/users/{id}/enable", apiServer.AdminUserEnable).Methods("POST")
+	adminMux.HandleFunc("/users/{id}/unlock", apiServer.AdminUserUnlock).Methods("POST")
+	adminMux.HandleFunc("/organisations/{id}/plan", apiServer.AdminOrganisationPlan).Methods("PUT")
+	adminMux.HandleFunc("/vehicles/{id}/refresh", apiServer.AdminVehicleRefresh).Methods("POST")
+	adminMux.HandleFunc("/jobs", apiServer.AdminJobList).Methods("GET")
+	adminMux.HandleFunc("/audit", apiServer.AdminAuditList).Methods("GET")
 
 	// mux.Handle("/", http.FileServer(http.Dir("./ui/build")))
 
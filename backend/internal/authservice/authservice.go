@@ -6,52 +6,68 @@ import (
 
 	"github.com/darkphnx/vehiclemanager/internal/models"
 	"github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// accessAudience marks a token as a login session, distinguishing it from other tokens signed
+// with the same secret
+const accessAudience = "access"
+
 type AuthService struct {
-	Secret          []byte
-	Issuer          string
-	ExpirationHours int64
+	Keys                 *KeySet
+	Issuer               string
+	AccessTokenLifetime  time.Duration
+	RefreshTokenLifetime time.Duration
 }
 
-// TokenClaim
+// TokenClaim is the body of an access token. The subject is the user's immutable ID and the token
+// ID names the session it belongs to.
 type TokenClaim struct {
-	UserID string
 	jwt.StandardClaims
 }
 
-func NewAuthService(secret string, expirationHours int64, issuer string) *AuthService {
+// UserID returns the ID of the user the token was issued to
+func (tc *TokenClaim) UserID() (primitive.ObjectID, error) {
+	return primitive.ObjectIDFromHex(tc.Subject)
+}
+
+// SessionLifetime is how long a session lasts without being refreshed
+func (as *AuthService) SessionLifetime() time.Duration {
+	return as.RefreshTokenLifetime
+}
+
+func NewAuthService(keys *KeySet, accessTokenLifetime, refreshTokenLifetime time.Duration, issuer string) *AuthService {
 	return &AuthService{
-		Secret:          []byte(secret),
-		ExpirationHours: expirationHours,
-		Issuer:          issuer,
+		Keys:                 keys,
+		AccessTokenLifetime:  accessTokenLifetime,
+		RefreshTokenLifetime: refreshTokenLifetime,
+		Issuer:               issuer,
 	}
 }
 
-func (as *AuthService) GenerateAccessToken(user *models.User) (string, error) {
-	userID := user.Email
-	expiresAt := time.Now().Add(time.Duration(as.ExpirationHours) * time.Hour)
+// GenerateAccessToken returns a signed token for the user bound to the given session
+func (as *AuthService) GenerateAccessToken(user *models.User, session *models.Session) (string, error) {
+	expiresAt := time.Now().Add(as.AccessTokenLifetime)
 
 	claim := TokenClaim{
-		userID,
 		jwt.StandardClaims{
+			Subject:   user.ID.Hex(),
+			Id:        session.ID.Hex(),
+			Audience:  accessAudience,
+			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: expiresAt.Unix(),
 			Issuer:    as.Issuer,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claim)
-
-	return token.SignedString(as.Secret)
+	return as.Keys.Sign(claim)
 }
 
 func (as *AuthService) VerifyAccessToken(signedToken string) (*TokenClaim, error) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&TokenClaim{},
-		func(token *jwt.Token) (interface{}, error) {
-			return as.Secret, nil
-		})
+		as.Keys.Keyfunc)
 
 	if err != nil {
 		return nil, err
@@ -68,5 +84,10 @@ func (as *AuthService) VerifyAccessToken(signedToken string) (*TokenClaim, error
 		return nil, err
 	}
 
+	if claims.Audience != accessAudience {
+		err = errors.New("JWT is not an access token")
+		return nil, err
+	}
+
 	return claims, nil
 }
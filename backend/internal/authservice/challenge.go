@@ -0,0 +1,50 @@
+package authservice
+
+import (
+	"errors"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const twoFactorChallengeAudience = "2fa-challenge"
+
+// TwoFactorChallengeLifetime is how long a user has to enter their second factor after their first
+const TwoFactorChallengeLifetime = 5 * time.Minute
+
+// GenerateTwoFactorChallengeToken returns a short-lived token proving the user has passed the
+// password step of login, to be exchanged for a session along with a second factor
+func (as *AuthService) GenerateTwoFactorChallengeToken(user *models.User) (string, error) {
+	claim := jwt.StandardClaims{
+		Subject:   user.ID.Hex(),
+		Audience:  twoFactorChallengeAudience,
+		ExpiresAt: time.Now().Add(TwoFactorChallengeLifetime).Unix(),
+		Issuer:    as.Issuer,
+	}
+
+	return as.Keys.Sign(claim)
+}
+
+// VerifyTwoFactorChallengeToken checks the signature, expiry and purpose of a challenge token
+func (as *AuthService) VerifyTwoFactorChallengeToken(signedToken string) (*jwt.StandardClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&jwt.StandardClaims{},
+		as.Keys.Keyfunc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*jwt.StandardClaims)
+	if !ok {
+		return nil, errors.New("Couldn't parse token")
+	}
+
+	if claims.Audience != twoFactorChallengeAudience {
+		return nil, errors.New("Token is not a two factor challenge token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,260 @@
+package authservice
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is a single key in a KeySet
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	RetiresAt time.Time
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// Retired returns true once the key may no longer be used to verify tokens
+func (k *SigningKey) Retired() bool {
+	return !k.RetiresAt.IsZero() && k.RetiresAt.Before(time.Now())
+}
+
+// NewHMACKey returns an HS256 key using the given shared secret
+func NewHMACKey(id, secret string) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// LoadPEMKey reads an RSA or Ed25519 key from a PEM file. A private key can both sign and verify,
+// a public key can only verify and is useful for keeping retired keys around.
+func LoadPEMKey(id, path string) (*SigningKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found in %s", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("Unsupported PEM block type %s in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: id}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.verifyKey = SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("Unsupported key type %T in %s", parsed, path)
+	}
+
+	return key, nil
+}
+
+// KeySet holds every key which tokens may be verified with, and the one new tokens are signed with.
+// Tokens name their key with the kid header so keys can be rotated without invalidating tokens.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewKeySet returns an empty KeySet
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys: make(map[string]*SigningKey),
+	}
+}
+
+// Add adds a key to the set, making it the signing key if active is true
+func (ks *KeySet) Add(key *SigningKey, active bool) error {
+	if _, exists := ks.keys[key.ID]; exists {
+		return fmt.Errorf("Duplicate key ID %s", key.ID)
+	}
+
+	if active {
+		if key.signKey == nil {
+			return fmt.Errorf("Key %s cannot sign tokens", key.ID)
+		}
+		ks.active = key
+	}
+
+	ks.keys[key.ID] = key
+	return nil
+}
+
+// Sign signs the claims with the active key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.active == nil {
+		return "", errors.New("No active signing key")
+	}
+
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+
+	return token.SignedString(ks.active.signKey)
+}
+
+// Keyfunc finds the verification key for a token by its kid header, refusing retired keys and
+// tokens whose algorithm doesn't match the key
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("Unknown signing key %q", kid)
+	}
+
+	if key.Retired() {
+		return nil, fmt.Errorf("Signing key %s has been retired", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method %s", token.Method.Alg())
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWK is a JSON Web Key as described in RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a set of JSON Web Keys
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of the asymmetric keys which are still valid. HMAC secrets are
+// never published.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range ks.keys {
+		if key.Retired() {
+			continue
+		}
+
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+
+		switch k := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// keySetConfig is the on-disk format read by LoadKeySet
+type keySetConfig struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID        string    `json:"id"`
+		Secret    string    `json:"secret"`
+		File      string    `json:"file"`
+		RetiresAt time.Time `json:"retires_at"`
+	} `json:"keys"`
+}
+
+// LoadKeySet reads a JSON keyset configuration. Each key has an id and either a secret for HS256 or
+// a PEM file for RS256/EdDSA, and optionally a retires_at time after which it is no longer
+// accepted. The key named by active signs new tokens.
+//
+//	{"active": "2021-02", "keys": [
+//	  {"id": "2021-02", "file": "/keys/2021-02.pem"},
+//	  {"id": "2021-01", "secret": "...", "retires_at": "2021-03-01T00:00:00Z"}
+//	]}
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config keySetConfig
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := NewKeySet()
+	for _, keyConfig := range config.Keys {
+		var key *SigningKey
+
+		if keyConfig.File != "" {
+			key, err = LoadPEMKey(keyConfig.ID, keyConfig.File)
+			if err != nil {
+				return nil, err
+			}
+		} else if keyConfig.Secret != "" {
+			key = NewHMACKey(keyConfig.ID, keyConfig.Secret)
+		} else {
+			return nil, fmt.Errorf("Key %s needs a secret or file", keyConfig.ID)
+		}
+
+		key.RetiresAt = keyConfig.RetiresAt
+
+		err = ks.Add(key, keyConfig.ID == config.Active)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ks.active == nil {
+		return nil, fmt.Errorf("Active key %q not found", config.Active)
+	}
+
+	return ks, nil
+}
@@ -0,0 +1,118 @@
+package authservice
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+
+	err := ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestKeySet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPath := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	edPath := writePEM(t, dir, "ed.pem", "PRIVATE KEY", edDER)
+
+	rsaSigningKey, err := LoadPEMKey("rsa", rsaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edSigningKey, err := LoadPEMKey("ed", edPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		signer  *SigningKey
+		verify  []*SigningKey
+		valid   bool
+		jwksLen int
+	}{
+		{name: "hmac", signer: NewHMACKey("hs", "secret"), valid: true},
+		{name: "rsa", signer: rsaSigningKey, valid: true, jwksLen: 1},
+		{name: "eddsa", signer: edSigningKey, valid: true, jwksLen: 1},
+		{name: "rotated to new key", signer: NewHMACKey("old", "secret"), verify: []*SigningKey{edSigningKey}, valid: true, jwksLen: 1},
+		{name: "retired key", signer: &SigningKey{ID: "retired", Method: jwt.SigningMethodHS256, signKey: []byte("s"), verifyKey: []byte("s"), RetiresAt: time.Now().Add(-time.Hour)}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			signing := NewKeySet()
+			if err := signing.Add(tc.signer, true); err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := signing.Sign(jwt.StandardClaims{Subject: "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Verify with a set where the signing key has been superseded by another
+			verifying := NewKeySet()
+			if err := verifying.Add(tc.signer, len(tc.verify) == 0); err != nil {
+				t.Fatalf("Expected no error adding the signing key but got '%s'", err)
+			}
+			for _, key := range tc.verify {
+				if err := verifying.Add(key, true); err != nil {
+					t.Fatalf("Expected no error adding key %s but got '%s'", key.ID, err)
+				}
+			}
+
+			_, err = jwt.ParseWithClaims(token, &jwt.StandardClaims{}, verifying.Keyfunc)
+			if (err == nil) != tc.valid {
+				t.Errorf("Expected valid %t but got error '%v'", tc.valid, err)
+			}
+
+			if len(verifying.JWKS().Keys) != tc.jwksLen {
+				t.Errorf("Expected %d public keys but got %d", tc.jwksLen, len(verifying.JWKS().Keys))
+			}
+		})
+	}
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	ks := NewKeySet()
+	if err := ks.Add(NewHMACKey("k", "secret"), true); err != nil {
+		t.Fatalf("Expected no error but got '%s'", err)
+	}
+
+	// A token signed with a different algorithm to the one its key uses must not be accepted
+	token := jwt.NewWithClaims(jwt.SigningMethodHS384, jwt.StandardClaims{})
+	token.Header["kid"] = "k"
+	signed, _ := token.SignedString([]byte("secret"))
+
+	_, err := jwt.Parse(signed, ks.Keyfunc)
+	if err == nil {
+		t.Error("Expected algorithm mismatch to be rejected")
+	}
+}
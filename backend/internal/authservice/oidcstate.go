@@ -0,0 +1,61 @@
+package authservice
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	oidcStateAudience = "oidc-login"
+	oidcStateLifetime = 10 * time.Minute
+)
+
+// OIDCStateClaim carries the values an OpenID Connect login needs to remember between redirecting
+// the user to the provider and handling the callback
+type OIDCStateClaim struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	jwt.StandardClaims
+}
+
+// GenerateOIDCStateToken returns a signed token holding the login's state, nonce and PKCE verifier
+func (as *AuthService) GenerateOIDCStateToken(state, nonce, codeVerifier string) (string, error) {
+	claim := OIDCStateClaim{
+		state,
+		nonce,
+		codeVerifier,
+		jwt.StandardClaims{
+			Audience:  oidcStateAudience,
+			ExpiresAt: time.Now().Add(oidcStateLifetime).Unix(),
+			Issuer:    as.Issuer,
+		},
+	}
+
+	return as.Keys.Sign(claim)
+}
+
+// VerifyOIDCStateToken checks the signature, expiry and purpose of a state token
+func (as *AuthService) VerifyOIDCStateToken(signedToken string) (*OIDCStateClaim, error) {
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&OIDCStateClaim{},
+		as.Keys.Keyfunc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*OIDCStateClaim)
+	if !ok {
+		return nil, errors.New("Couldn't parse token")
+	}
+
+	if claims.Audience != oidcStateAudience {
+		return nil, errors.New("Token is not an OIDC state token")
+	}
+
+	return claims, nil
+}
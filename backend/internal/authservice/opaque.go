@@ -0,0 +1,32 @@
+package authservice
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// opaqueTokenBytes is the amount of randomness in an opaque token
+const opaqueTokenBytes = 32
+
+// GenerateOpaqueToken returns a random URL-safe token along with the hash which should be stored in
+// place of it. The token itself is only ever shown to the user.
+func GenerateOpaqueToken() (token string, hash string, err error) {
+	buf := make([]byte, opaqueTokenBytes)
+
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the SHA-256 hash of a token for storage and lookup
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package authservice
+
+import (
+	"errors"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	emailVerificationAudience = "verify-email"
+	emailVerificationLifetime = 48 * time.Hour
+)
+
+// EmailVerificationClaim proves ownership of an e-mail address. The address is included so a token
+// issued before the user changes their e-mail cannot verify the new one.
+type EmailVerificationClaim struct {
+	Email string
+	jwt.StandardClaims
+}
+
+// GenerateEmailVerificationToken returns a signed token proving the user owns the given address,
+// which is either their current address or one they are changing to
+func (as *AuthService) GenerateEmailVerificationToken(user *models.User, email string) (string, error) {
+	claim := EmailVerificationClaim{
+		email,
+		jwt.StandardClaims{
+			Subject:   user.ID.Hex(),
+			Audience:  emailVerificationAudience,
+			ExpiresAt: time.Now().Add(emailVerificationLifetime).Unix(),
+			Issuer:    as.Issuer,
+		},
+	}
+
+	return as.Keys.Sign(claim)
+}
+
+// VerifyEmailVerificationToken checks the signature, expiry and purpose of a verification token
+func (as *AuthService) VerifyEmailVerificationToken(signedToken string) (*EmailVerificationClaim, error) {
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&EmailVerificationClaim{},
+		as.Keys.Keyfunc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*EmailVerificationClaim)
+	if !ok {
+		return nil, errors.New("Couldn't parse token")
+	}
+
+	if claims.Audience != emailVerificationAudience {
+		return nil, errors.New("Token is not an e-mail verification token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer sends plain text e-mail through an SMTP relay
+type Mailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewMailer returns a new Mailer. If host is empty messages are written to the log instead of
+// being sent, which is useful for development.
+func NewMailer(host string, port int, username, password, from string) *Mailer {
+	return &Mailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers a plain text message to a single recipient
+func (m *Mailer) Send(to, subject, body string) error {
+	if m.host == "" {
+		log.Printf("Mail to %s: %s\n%s\n", to, subject, body)
+		return nil
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", m.host, m.port)
+	return smtp.SendMail(addr, auth, m.from, []string{to}, m.buildMessage(to, subject, body))
+}
+
+func (m *Mailer) buildMessage(to, subject, body string) []byte {
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+
+	return []byte(msg.String())
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// API token scopes
+const (
+	ScopeVehiclesRead   = "vehicles:read"
+	ScopeVehiclesManage = "vehicles:manage"
+	ScopeNotifications  = "notifications"
+)
+
+// APITokenScopes lists every scope a token may be granted
+var APITokenScopes = []string{ScopeVehiclesRead, ScopeVehiclesManage, ScopeNotifications}
+
+// APIToken is a personal access token for scripting against the API. Only a hash of the token is
+// stored, along with a short prefix so users can tell their tokens apart.
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"-"`
+	Name       string             `bson:"name"`
+	Prefix     string             `bson:"prefix"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	Scopes     []string           `bson:"scopes"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	LastUsedAt time.Time          `bson:"last_used_at"`
+}
+
+// HasScope returns true if the token was granted the scope. Managing vehicles implies reading them.
+func (t *APIToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope || (scope == ScopeVehiclesRead && granted == ScopeVehiclesManage) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateAPIToken writes a new API token to the database
+func CreateAPIToken(db *Database, token *APIToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := apiTokenCollection(db).InsertOne(ctx, token)
+	return err
+}
+
+// GetAPIToken fetches a token by the hash of its value
+func GetAPIToken(db *Database, tokenHash string) (*APIToken, error) {
+	var token APIToken
+
+	err := apiTokenCollection(db).FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+
+	return &token, err
+}
+
+// GetUserAPITokens fetches all tokens belonging to a user, newest first
+func GetUserAPITokens(db *Database, userID primitive.ObjectID) ([]*APIToken, error) {
+	var tokens []*APIToken
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	cur, err := apiTokenCollection(db).Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return tokens, err
+	}
+
+	err = cur.All(ctx, &tokens)
+	return tokens, err
+}
+
+// TouchAPIToken records that a token has just been used
+func TouchAPIToken(db *Database, token *APIToken) error {
+	token.LastUsedAt = time.Now()
+
+	_, err := apiTokenCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": token.ID},
+		bson.M{"$set": bson.M{"last_used_at": token.LastUsedAt}},
+	)
+
+	return err
+}
+
+// DeleteUserAPIToken revokes one of a user's tokens
+func DeleteUserAPIToken(db *Database, userID, tokenID primitive.ObjectID) error {
+	res, err := apiTokenCollection(db).DeleteOne(ctx, bson.M{"_id": tokenID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// DeleteUserAPITokens revokes every token belonging to a user
+func DeleteUserAPITokens(db *Database, userID primitive.ObjectID) error {
+	_, err := apiTokenCollection(db).DeleteMany(ctx, bson.M{"user_id": userID})
+
+	return err
+}
+
+func apiTokenCollection(db *Database) *mongo.Collection {
+	return db.Collection("api_tokens")
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Audit actions
+const (
+	AuditLogin                    = "auth.login"
+	AuditLoginFailed              = "auth.login_failed"
+	AuditLogout                   = "auth.logout"
+	AuditSignup                   = "auth.signup"
+	AuditPasswordReset            = "auth.password_reset"
+	AuditSessionRejected          = "auth.session_rejected"
+	AuditAPITokenRejected         = "auth.api_token_rejected"
+	AuditAccountExported          = "account.exported"
+	AuditAccountDeletionScheduled = "account.deletion_scheduled"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountDeleted           = "account.deleted"
+	AuditVehicleCreated           = "vehicle.created"
+	AuditVehicleDeleted           = "vehicle.deleted"
+	AuditVehicleShared            = "vehicle.shared"
+	AuditVehicleShareRevoked      = "vehicle.share_revoked"
+	AuditShareLinkCreated         = "share_link.created"
+	AuditShareLinkRevoked         = "share_link.revoked"
+	AuditAPITokenCreated          = "api_token.created"
+	AuditAPITokenDeleted          = "api_token.deleted"
+	AuditAdminPlanChanged         = "admin.plan_changed"
+	AuditAdminUserDisabled        = "admin.user_disabled"
+	AuditAdminUserEnabled         = "admin.user_enabled"
+	AuditAdminUserUnlocked        = "admin.user_unlocked"
+	AuditAdminVehicleRefreshed    = "admin.vehicle_refreshed"
+)
+
+// AuditEntry records who did what to which record. Entries are only ever appended.
+type AuditEntry struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	ActorID    primitive.ObjectID `bson:"actor_id,omitempty"`
+	Action     string             `bson:"action"`
+	TargetType string             `bson:"target_type"`
+	TargetID   string             `bson:"target_id"`
+	Details    string             `bson:"details"`
+	IPAddress  string             `bson:"ip_address"`
+	UserAgent  string             `bson:"user_agent"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+// CreateAuditEntry appends an entry to the audit log
+func CreateAuditEntry(db *Database, entry *AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+
+	_, err := auditEntryCollection(db).InsertOne(ctx, entry)
+	return err
+}
+
+// GetAuditEntries fetches up to limit entries, newest first. Paging continues from the entry
+// before the given ID, or from the newest if it is zero.
+func GetAuditEntries(db *Database, before primitive.ObjectID, limit int64) ([]*AuditEntry, error) {
+	return getAuditEntries(db, bson.M{}, before, limit)
+}
+
+// GetUserAuditEntries fetches up to limit entries for actions taken by a user or against their
+// account, newest first, paging in the same way as GetAuditEntries. The entries are meant to be
+// shown to the user, so are redacted for them.
+func GetUserAuditEntries(db *Database, userID primitive.ObjectID, before primitive.ObjectID, limit int64) ([]*AuditEntry, error) {
+	query := bson.M{
+		"$or": bson.A{
+			bson.M{"actor_id": userID},
+			bson.M{"target_type": "user", "target_id": userID.Hex()},
+		},
+	}
+
+	entries, err := getAuditEntries(db, query, before, limit)
+	for _, entry := range entries {
+		entry.redactFor(userID)
+	}
+
+	return entries, err
+}
+
+// redactFor hides who took an action from the user it was taken against, such as an administrator
+// disabling their account. Anonymous actions, like failed logins, keep their address so the user
+// can see where they came from.
+func (e *AuditEntry) redactFor(userID primitive.ObjectID) {
+	if e.ActorID.IsZero() || e.ActorID == userID {
+		return
+	}
+
+	e.ActorID = primitive.NilObjectID
+	e.IPAddress = ""
+	e.UserAgent = ""
+}
+
+func auditEntryCollection(db *Database) *mongo.Collection {
+	return db.Collection("audit_entries")
+}
+
+func getAuditEntries(db *Database, query bson.M, before primitive.ObjectID, limit int64) ([]*AuditEntry, error) {
+	var entries []*AuditEntry
+
+	if !before.IsZero() {
+		query["_id"] = bson.M{"$lt": before}
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit)
+
+	cur, err := auditEntryCollection(db).Find(ctx, query, opts)
+	if err != nil {
+		return entries, err
+	}
+
+	err = cur.All(ctx, &entries)
+
+	return entries, err
+}
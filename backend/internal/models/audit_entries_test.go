@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditEntryRedactFor(t *testing.T) {
+	userID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+
+	testCases := []struct {
+		name     string
+		actorID  primitive.ObjectID
+		redacted bool
+	}{
+		{name: "own action", actorID: userID, redacted: false},
+		{name: "anonymous action", actorID: primitive.NilObjectID, redacted: false},
+		{name: "administrator action", actorID: adminID, redacted: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := AuditEntry{ActorID: tc.actorID, IPAddress: "192.0.2.1", UserAgent: "curl/7.68.0"}
+			entry.redactFor(userID)
+
+			if tc.redacted {
+				if !entry.ActorID.IsZero() || entry.IPAddress != "" || entry.UserAgent != "" {
+					t.Errorf("entry was not redacted: %+v", entry)
+				}
+			} else {
+				if entry.ActorID != tc.actorID || entry.IPAddress != "192.0.2.1" || entry.UserAgent != "curl/7.68.0" {
+					t.Errorf("entry was redacted: %+v", entry)
+				}
+			}
+		})
+	}
+}
@@ -31,5 +31,22 @@ func InitDB(connectionString string) (*Database, error) {
 		db.Database("vehicle-manager"),
 	}
 
+	err = createIndexes(&database)
+	if err != nil {
+		return nil, err
+	}
+
 	return &database, nil
 }
+
+// createIndexes makes sure the indexes that queries rely on exist. Indexes which already exist are
+// left alone.
+func createIndexes(db *Database) error {
+	_, err := vehicleCollection(db).Indexes().CreateMany(ctx, vehicleIndexes())
+	if err != nil {
+		return err
+	}
+
+	_, err = vehicleShareCollection(db).Indexes().CreateMany(ctx, vehicleShareIndexes())
+	return err
+}
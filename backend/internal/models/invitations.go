@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Invitation asks someone, by e-mail address, to join an organisation with a given role. Only the
+// hash of the token sent to them is stored.
+type Invitation struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	OrganisationID primitive.ObjectID `bson:"organisation_id"`
+	Email          string             `bson:"email"`
+	Role           string             `bson:"role"`
+	InvitedBy      primitive.ObjectID `bson:"invited_by"`
+	TokenHash      string             `bson:"token_hash" json:"-"`
+	ExpiresAt      time.Time          `bson:"expires_at"`
+	CreatedAt      time.Time          `bson:"created_at"`
+}
+
+// CreateInvitation writes a new invitation to the database
+func CreateInvitation(db *Database, invitation *Invitation) error {
+	invitation.ID = primitive.NewObjectID()
+	invitation.CreatedAt = time.Now()
+
+	_, err := invitationCollection(db).InsertOne(ctx, invitation)
+	return err
+}
+
+// GetOrganisationInvitations fetches the unexpired invitations to an organisation
+func GetOrganisationInvitations(db *Database, organisationID primitive.ObjectID) ([]*Invitation, error) {
+	var invitations []*Invitation
+
+	query := bson.M{
+		"organisation_id": organisationID,
+		"expires_at":      bson.M{"$gt": time.Now()},
+	}
+
+	cur, err := invitationCollection(db).Find(ctx, query)
+	if err != nil {
+		return invitations, err
+	}
+
+	err = cur.All(ctx, &invitations)
+
+	return invitations, err
+}
+
+// GetInvitation fetches an unexpired invitation by token hash
+func GetInvitation(db *Database, tokenHash string) (*Invitation, error) {
+	var invitation Invitation
+
+	query := bson.M{
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	err := invitationCollection(db).FindOne(ctx, query).Decode(&invitation)
+
+	return &invitation, err
+}
+
+// ConsumeInvitation finds an unexpired invitation by token hash and deletes it in one operation,
+// so an invitation can only ever be accepted once
+func ConsumeInvitation(db *Database, tokenHash string) (*Invitation, error) {
+	var invitation Invitation
+
+	query := bson.M{
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	err := invitationCollection(db).FindOneAndDelete(ctx, query).Decode(&invitation)
+
+	return &invitation, err
+}
+
+// DeleteOrganisationInvitation withdraws one of an organisation's invitations
+func DeleteOrganisationInvitation(db *Database, organisationID, invitationID primitive.ObjectID) error {
+	query := bson.M{
+		"_id":             invitationID,
+		"organisation_id": organisationID,
+	}
+
+	result, err := invitationCollection(db).DeleteOne(ctx, query)
+	if err == nil && result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return err
+}
+
+// DeleteOrganisationInvitations withdraws every invitation to an organisation
+func DeleteOrganisationInvitations(db *Database, organisationID primitive.ObjectID) error {
+	_, err := invitationCollection(db).DeleteMany(ctx, bson.M{"organisation_id": organisationID})
+
+	return err
+}
+
+func invitationCollection(db *Database) *mongo.Collection {
+	return db.Collection("invitations")
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobRun records the most recent run of a background job
+type JobRun struct {
+	Name       string    `bson:"_id"`
+	Running    bool      `bson:"running"`
+	StartedAt  time.Time `bson:"started_at"`
+	FinishedAt time.Time `bson:"finished_at"`
+	Processed  int       `bson:"processed"`
+	Failed     int       `bson:"failed"`
+	LastError  string    `bson:"last_error"`
+}
+
+// SaveJobRun writes the state of a job's current or most recent run
+func SaveJobRun(db *Database, run *JobRun) error {
+	_, err := jobRunCollection(db).ReplaceOne(
+		ctx,
+		bson.M{"_id": run.Name},
+		run,
+		options.Replace().SetUpsert(true),
+	)
+
+	return err
+}
+
+// GetJobRuns fetches the most recent run of every background job
+func GetJobRuns(db *Database) ([]*JobRun, error) {
+	var runs []*JobRun
+
+	cur, err := jobRunCollection(db).Find(ctx, bson.M{})
+	if err != nil {
+		return runs, err
+	}
+
+	err = cur.All(ctx, &runs)
+
+	return runs, err
+}
+
+func jobRunCollection(db *Database) *mongo.Collection {
+	return db.Collection("job_runs")
+}
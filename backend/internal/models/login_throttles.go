@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginThrottle counts recent failed logins for an IP address or account. Keeping these in the
+// database means throttling and lockouts are shared between replicas and survive restarts.
+type LoginThrottle struct {
+	Key           string    `bson:"_id"`
+	Failures      int       `bson:"failures"`
+	LastFailureAt time.Time `bson:"last_failure_at"`
+	LockedUntil   time.Time `bson:"locked_until"`
+}
+
+// Locked returns true if logins are currently refused outright
+func (lt *LoginThrottle) Locked() bool {
+	return lt.LockedUntil.After(time.Now())
+}
+
+// GetLoginThrottle fetches the throttle for a key. A throttle with no failures is returned if none
+// has been recorded.
+func GetLoginThrottle(db *Database, key string) (*LoginThrottle, error) {
+	throttle := LoginThrottle{Key: key}
+
+	err := loginThrottleCollection(db).FindOne(ctx, bson.M{"_id": key}).Decode(&throttle)
+	if err == mongo.ErrNoDocuments {
+		return &throttle, nil
+	}
+
+	return &throttle, err
+}
+
+// RecordLoginFailure increments the failure count for a key, starting afresh if the previous
+// failures are older than window and any lockout has passed
+func RecordLoginFailure(db *Database, key string, window time.Duration) (*LoginThrottle, error) {
+	now := time.Now()
+
+	_, err := loginThrottleCollection(db).DeleteOne(ctx, bson.M{
+		"_id":             key,
+		"last_failure_at": bson.M{"$lt": now.Add(-window)},
+		"locked_until":    bson.M{"$lt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var throttle LoginThrottle
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err = loginThrottleCollection(db).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc":         bson.M{"failures": 1},
+			"$set":         bson.M{"last_failure_at": now},
+			"$setOnInsert": bson.M{"locked_until": time.Time{}},
+		},
+		opts,
+	).Decode(&throttle)
+
+	return &throttle, err
+}
+
+// LockLogin refuses logins for the key until the given time
+func LockLogin(db *Database, key string, until time.Time) error {
+	_, err := loginThrottleCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"locked_until": until}},
+	)
+
+	return err
+}
+
+// ClearLoginThrottle forgets all failures for a key, lifting any lockout
+func ClearLoginThrottle(db *Database, key string) error {
+	_, err := loginThrottleCollection(db).DeleteOne(ctx, bson.M{"_id": key})
+
+	return err
+}
+
+func loginThrottleCollection(db *Database) *mongo.Collection {
+	return db.Collection("login_throttles")
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CachedLookup holds the details fetched for a one-off registration lookup, so repeated lookups
+// don't each call the upstream APIs
+type CachedLookup struct {
+	RegistrationNumber string    `bson:"_id"`
+	Vehicle            Vehicle   `bson:"vehicle"`
+	FetchedAt          time.Time `bson:"fetched_at"`
+}
+
+// GetCachedLookup fetches the cached details for a registration if they are newer than maxAge
+func GetCachedLookup(db *Database, registrationNumber string, maxAge time.Duration) (*CachedLookup, error) {
+	var lookup CachedLookup
+
+	query := bson.M{
+		"_id":        registrationNumber,
+		"fetched_at": bson.M{"$gt": time.Now().Add(-maxAge)},
+	}
+
+	err := lookupCacheCollection(db).FindOne(ctx, query).Decode(&lookup)
+
+	return &lookup, err
+}
+
+// SaveCachedLookup stores freshly fetched details for a registration, replacing any already cached
+func SaveCachedLookup(db *Database, lookup *CachedLookup) error {
+	lookup.FetchedAt = time.Now()
+
+	_, err := lookupCacheCollection(db).ReplaceOne(
+		ctx,
+		bson.M{"_id": lookup.RegistrationNumber},
+		lookup,
+		options.Replace().SetUpsert(true),
+	)
+
+	return err
+}
+
+func lookupCacheCollection(db *Database) *mongo.Collection {
+	return db.Collection("lookup_cache")
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Roles a user can hold within an organisation, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// Roles lists every valid organisation role
+var Roles = []string{RoleOwner, RoleAdmin, RoleMember, RoleViewer}
+
+// Membership gives a user a role within an organisation
+type Membership struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	OrganisationID primitive.ObjectID `bson:"organisation_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	Role           string             `bson:"role"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// CanManageVehicles is true for roles which may add, remove and change the organisation's vehicles
+func (m *Membership) CanManageVehicles() bool {
+	return m.Role == RoleOwner || m.Role == RoleAdmin || m.Role == RoleMember
+}
+
+// CanManageMembers is true for roles which may invite, change and remove members
+func (m *Membership) CanManageMembers() bool {
+	return m.Role == RoleOwner || m.Role == RoleAdmin
+}
+
+// CreateMembership writes a new membership to the database
+func CreateMembership(db *Database, membership *Membership) error {
+	membership.ID = primitive.NewObjectID()
+	membership.CreatedAt = time.Now()
+	membership.UpdatedAt = time.Now()
+
+	_, err := membershipCollection(db).InsertOne(ctx, membership)
+	return err
+}
+
+// GetMembership fetches a user's membership of an organisation
+func GetMembership(db *Database, organisationID, userID primitive.ObjectID) (*Membership, error) {
+	var membership Membership
+
+	query := bson.M{
+		"organisation_id": organisationID,
+		"user_id":         userID,
+	}
+
+	err := membershipCollection(db).FindOne(ctx, query).Decode(&membership)
+
+	return &membership, err
+}
+
+// GetUserMemberships fetches every organisation membership a user holds
+func GetUserMemberships(db *Database, userID primitive.ObjectID) ([]*Membership, error) {
+	return getMemberships(db, bson.M{"user_id": userID})
+}
+
+// GetOrganisationMemberships fetches every membership of an organisation
+func GetOrganisationMemberships(db *Database, organisationID primitive.ObjectID) ([]*Membership, error) {
+	return getMemberships(db, bson.M{"organisation_id": organisationID})
+}
+
+// OrganisationOwnerCount counts the owners of an organisation
+func OrganisationOwnerCount(db *Database, organisationID primitive.ObjectID) int64 {
+	query := bson.M{
+		"organisation_id": organisationID,
+		"role":            RoleOwner,
+	}
+
+	count, err := membershipCollection(db).CountDocuments(ctx, query)
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+// UserOwnedOrganisationCount counts the organisations a user is an owner of
+func UserOwnedOrganisationCount(db *Database, userID primitive.ObjectID) int64 {
+	query := bson.M{
+		"user_id": userID,
+		"role":    RoleOwner,
+	}
+
+	count, err := membershipCollection(db).CountDocuments(ctx, query)
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+// UpdateMembership replaces the existing membership
+func UpdateMembership(db *Database, membership *Membership) error {
+	membership.UpdatedAt = time.Now()
+
+	_, err := membershipCollection(db).ReplaceOne(ctx, bson.M{"_id": membership.ID}, membership)
+
+	return err
+}
+
+// DeleteMembership removes a user from an organisation
+func DeleteMembership(db *Database, membership *Membership) error {
+	_, err := membershipCollection(db).DeleteOne(ctx, bson.M{"_id": membership.ID})
+
+	return err
+}
+
+// DeleteOrganisationMemberships removes every member of an organisation
+func DeleteOrganisationMemberships(db *Database, organisationID primitive.ObjectID) error {
+	_, err := membershipCollection(db).DeleteMany(ctx, bson.M{"organisation_id": organisationID})
+
+	return err
+}
+
+func membershipCollection(db *Database) *mongo.Collection {
+	return db.Collection("memberships")
+}
+
+func getMemberships(db *Database, query bson.M) ([]*Membership, error) {
+	var memberships []*Membership
+
+	cur, err := membershipCollection(db).Find(ctx, query)
+	if err != nil {
+		return memberships, err
+	}
+
+	err = cur.All(ctx, &memberships)
+
+	return memberships, err
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Organisation is a group of users who look after a shared pool of vehicles. Its plan sets the
+// limits on those vehicles, in the same way as a user's plan does for their own.
+type Organisation struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Name      string             `bson:"name"`
+	Plan      string             `bson:"plan"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// CreateOrganisation writes a new organisation to the database
+func CreateOrganisation(db *Database, organisation *Organisation) error {
+	organisation.ID = primitive.NewObjectID()
+	organisation.CreatedAt = time.Now()
+	organisation.UpdatedAt = time.Now()
+
+	_, err := organisationCollection(db).InsertOne(ctx, organisation)
+	return err
+}
+
+// GetOrganisation fetches an organisation by ID
+func GetOrganisation(db *Database, organisationID primitive.ObjectID) (*Organisation, error) {
+	var organisation Organisation
+
+	err := organisationCollection(db).FindOne(ctx, bson.M{"_id": organisationID}).Decode(&organisation)
+
+	return &organisation, err
+}
+
+// GetOrganisations fetches every organisation with one of the given IDs
+func GetOrganisations(db *Database, organisationIDs []primitive.ObjectID) ([]*Organisation, error) {
+	var organisations []*Organisation
+
+	cur, err := organisationCollection(db).Find(ctx, bson.M{"_id": bson.M{"$in": organisationIDs}})
+	if err != nil {
+		return organisations, err
+	}
+
+	err = cur.All(ctx, &organisations)
+
+	return organisations, err
+}
+
+// UpdateOrganisation replaces the existing organisation
+func UpdateOrganisation(db *Database, organisation *Organisation) error {
+	organisation.UpdatedAt = time.Now()
+
+	_, err := organisationCollection(db).ReplaceOne(ctx, bson.M{"_id": organisation.ID}, organisation)
+
+	return err
+}
+
+// DeleteOrganisation removes an organisation. Its vehicles, members and invitations must be
+// removed separately.
+func DeleteOrganisation(db *Database, organisation *Organisation) error {
+	_, err := organisationCollection(db).DeleteOne(ctx, bson.M{"_id": organisation.ID})
+
+	return err
+}
+
+// PurgeOrganisation deletes an organisation along with its vehicles, invitations and memberships
+func PurgeOrganisation(db *Database, organisation *Organisation) error {
+	vehicles, err := GetOrganisationVehicles(db, organisation.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, vehicle := range vehicles {
+		err = PurgeVehicle(db, vehicle)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = DeleteOrganisationInvitations(db, organisation.ID)
+	if err != nil {
+		return err
+	}
+
+	err = DeleteOrganisationMemberships(db, organisation.ID)
+	if err != nil {
+		return err
+	}
+
+	return DeleteOrganisation(db, organisation)
+}
+
+func organisationCollection(db *Database) *mongo.Collection {
+	return db.Collection("organisations")
+}
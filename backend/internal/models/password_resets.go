@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PasswordReset is a single-use request to reset a user's password. Only the hash of the token
+// sent to the user is stored.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	TokenHash string             `bson:"token_hash"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// CreatePasswordReset writes a new password reset to the database
+func CreatePasswordReset(db *Database, reset *PasswordReset) error {
+	reset.ID = primitive.NewObjectID()
+	reset.CreatedAt = time.Now()
+
+	_, err := passwordResetCollection(db).InsertOne(ctx, reset)
+	return err
+}
+
+// ConsumePasswordReset finds an unexpired reset by token hash and deletes it in one operation, so
+// a token can only ever be used once
+func ConsumePasswordReset(db *Database, tokenHash string) (*PasswordReset, error) {
+	var reset PasswordReset
+
+	query := bson.M{
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	err := passwordResetCollection(db).FindOneAndDelete(ctx, query).Decode(&reset)
+
+	return &reset, err
+}
+
+// DeleteUserPasswordResets removes any outstanding resets for a user
+func DeleteUserPasswordResets(db *Database, userID primitive.ObjectID) error {
+	_, err := passwordResetCollection(db).DeleteMany(ctx, bson.M{"user_id": userID})
+
+	return err
+}
+
+func passwordResetCollection(db *Database) *mongo.Collection {
+	return db.Collection("password_resets")
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimit counts requests made under a key within a fixed window
+type RateLimit struct {
+	Key         string    `bson:"_id"`
+	Requests    int64     `bson:"requests"`
+	WindowStart time.Time `bson:"window_start"`
+}
+
+// ResetsAt is when the window ends and the count starts afresh
+func (rl *RateLimit) ResetsAt(window time.Duration) time.Time {
+	return rl.WindowStart.Add(window)
+}
+
+// HitRateLimit counts a request against a key, starting a new window if the previous one has ended
+func HitRateLimit(db *Database, key string, window time.Duration) (*RateLimit, error) {
+	now := time.Now()
+
+	_, err := rateLimitCollection(db).DeleteOne(ctx, bson.M{
+		"_id":          key,
+		"window_start": bson.M{"$lt": now.Add(-window)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rateLimit RateLimit
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err = rateLimitCollection(db).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc":         bson.M{"requests": 1},
+			"$setOnInsert": bson.M{"window_start": now},
+		},
+		opts,
+	).Decode(&rateLimit)
+
+	return &rateLimit, err
+}
+
+func rateLimitCollection(db *Database) *mongo.Collection {
+	return db.Collection("rate_limits")
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token which has already been exchanged is
+// presented again, indicating it may have been stolen
+var ErrRefreshTokenReused = errors.New("Refresh token has already been used")
+
+// RefreshToken is a single-use token exchanged for a new access token. All refresh tokens issued
+// for a session form a family, which is revoked as a whole if any member is reused.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	SessionID primitive.ObjectID `bson:"session_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	TokenHash string             `bson:"token_hash"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    time.Time          `bson:"used_at"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// CreateRefreshToken writes a new refresh token to the database
+func CreateRefreshToken(db *Database, token *RefreshToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := refreshTokenCollection(db).InsertOne(ctx, token)
+	return err
+}
+
+// GetRefreshToken fetches a refresh token by the hash of its value
+func GetRefreshToken(db *Database, tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+
+	err := refreshTokenCollection(db).FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+
+	return &token, err
+}
+
+// UseRefreshToken marks a refresh token as exchanged. ErrRefreshTokenReused is returned if it had
+// already been used, including by a concurrent request.
+func UseRefreshToken(db *Database, token *RefreshToken) error {
+	token.UsedAt = time.Now()
+
+	res, err := refreshTokenCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": token.ID, "used_at": time.Time{}},
+		bson.M{"$set": bson.M{"used_at": token.UsedAt}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.ModifiedCount == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+// DeleteSessionRefreshTokens removes the whole token family for a session
+func DeleteSessionRefreshTokens(db *Database, sessionID primitive.ObjectID) error {
+	_, err := refreshTokenCollection(db).DeleteMany(ctx, bson.M{"session_id": sessionID})
+
+	return err
+}
+
+// DeleteUserRefreshTokens removes every refresh token issued to a user
+func DeleteUserRefreshTokens(db *Database, userID primitive.ObjectID) error {
+	_, err := refreshTokenCollection(db).DeleteMany(ctx, bson.M{"user_id": userID})
+
+	return err
+}
+
+func refreshTokenCollection(db *Database) *mongo.Collection {
+	return db.Collection("refresh_tokens")
+}
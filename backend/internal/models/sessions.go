@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Session is a signed-in device. Access tokens carry the session ID so they can be revoked
+// server-side before they expire.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"-"`
+	UserAgent  string             `bson:"user_agent"`
+	IPAddress  string             `bson:"ip_address"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	LastSeenAt time.Time          `bson:"last_seen_at"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+	RevokedAt  time.Time          `bson:"revoked_at" json:"-"`
+}
+
+// Active returns true if the session has neither expired nor been revoked
+func (s *Session) Active() bool {
+	return s.RevokedAt.IsZero() && s.ExpiresAt.After(time.Now())
+}
+
+// CreateSession writes a new session to the database
+func CreateSession(db *Database, session *Session) error {
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = time.Now()
+
+	_, err := sessionCollection(db).InsertOne(ctx, session)
+	return err
+}
+
+// GetSession fetches a session by ID
+func GetSession(db *Database, id primitive.ObjectID) (*Session, error) {
+	var session Session
+
+	err := sessionCollection(db).FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+
+	return &session, err
+}
+
+// GetUserSessions fetches all active sessions for a user, most recently used first
+func GetUserSessions(db *Database, userID primitive.ObjectID) ([]*Session, error) {
+	var sessions []*Session
+
+	query := bson.M{
+		"user_id":    userID,
+		"revoked_at": time.Time{},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.M{"last_seen_at": -1})
+
+	cur, err := sessionCollection(db).Find(ctx, query, opts)
+	if err != nil {
+		return sessions, err
+	}
+
+	err = cur.All(ctx, &sessions)
+	return sessions, err
+}
+
+// TouchSession records that a session has just been used
+func TouchSession(db *Database, session *Session) error {
+	session.LastSeenAt = time.Now()
+
+	_, err := sessionCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": session.ID},
+		bson.M{"$set": bson.M{"last_seen_at": session.LastSeenAt}},
+	)
+
+	return err
+}
+
+// RenewSession extends the expiry of a session each time its refresh token is rotated
+func RenewSession(db *Database, session *Session, expiresAt time.Time) error {
+	session.LastSeenAt = time.Now()
+	session.ExpiresAt = expiresAt
+
+	_, err := sessionCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": session.ID},
+		bson.M{"$set": bson.M{"last_seen_at": session.LastSeenAt, "expires_at": session.ExpiresAt}},
+	)
+
+	return err
+}
+
+// RevokeUserSession revokes a single session belonging to the user
+func RevokeUserSession(db *Database, userID, sessionID primitive.ObjectID) error {
+	res, err := sessionCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": sessionID, "user_id": userID, "revoked_at": time.Time{}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every session belonging to the user
+func RevokeUserSessions(db *Database, userID primitive.ObjectID) error {
+	_, err := sessionCollection(db).UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "revoked_at": time.Time{}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+
+	return err
+}
+
+// DeleteUserSessions removes every session belonging to the user, including revoked ones
+func DeleteUserSessions(db *Database, userID primitive.ObjectID) error {
+	_, err := sessionCollection(db).DeleteMany(ctx, bson.M{"user_id": userID})
+
+	return err
+}
+
+func sessionCollection(db *Database) *mongo.Collection {
+	return db.Collection("sessions")
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShareLink gives anyone holding its token a read-only view of a vehicle's history. Only a hash of
+// the token is stored, along with a short prefix so links can be told apart. A zero ExpiresAt
+// never expires.
+type ShareLink struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	VehicleID    primitive.ObjectID `bson:"vehicle_id" json:"-"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"-"`
+	Prefix       string             `bson:"prefix"`
+	TokenHash    string             `bson:"token_hash" json:"-"`
+	ExpiresAt    time.Time          `bson:"expires_at"`
+	Views        int64              `bson:"views"`
+	LastViewedAt time.Time          `bson:"last_viewed_at"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// CreateShareLink writes a new share link to the database
+func CreateShareLink(db *Database, link *ShareLink) error {
+	link.ID = primitive.NewObjectID()
+	link.CreatedAt = time.Now()
+
+	_, err := shareLinkCollection(db).InsertOne(ctx, link)
+	return err
+}
+
+// ViewShareLink finds an unexpired link by token hash and counts a view of it in one operation
+func ViewShareLink(db *Database, tokenHash string) (*ShareLink, error) {
+	var link ShareLink
+
+	now := time.Now()
+
+	query := bson.M{
+		"token_hash": tokenHash,
+		"$or": bson.A{
+			bson.M{"expires_at": time.Time{}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"views": 1},
+		"$set": bson.M{"last_viewed_at": now},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := shareLinkCollection(db).FindOneAndUpdate(ctx, query, update, opts).Decode(&link)
+
+	return &link, err
+}
+
+// GetVehicleShareLinks fetches every share link for a vehicle, including expired ones
+func GetVehicleShareLinks(db *Database, vehicleID primitive.ObjectID) ([]*ShareLink, error) {
+	var links []*ShareLink
+
+	cur, err := shareLinkCollection(db).Find(ctx, bson.M{"vehicle_id": vehicleID})
+	if err != nil {
+		return links, err
+	}
+
+	err = cur.All(ctx, &links)
+
+	return links, err
+}
+
+// DeleteShareLink revokes one of a vehicle's share links
+func DeleteShareLink(db *Database, vehicleID, linkID primitive.ObjectID) error {
+	result, err := shareLinkCollection(db).DeleteOne(ctx, bson.M{"_id": linkID, "vehicle_id": vehicleID})
+	if err == nil && result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return err
+}
+
+// DeleteVehicleShareLinks revokes every share link for a vehicle
+func DeleteVehicleShareLinks(db *Database, vehicleID primitive.ObjectID) error {
+	_, err := shareLinkCollection(db).DeleteMany(ctx, bson.M{"vehicle_id": vehicleID})
+
+	return err
+}
+
+func shareLinkCollection(db *Database) *mongo.Collection {
+	return db.Collection("share_links")
+}
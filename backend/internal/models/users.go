@@ -1,20 +1,52 @@
 package models
 
 import (
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type User struct {
-	ID             primitive.ObjectID `bson:"_id"`
-	Email          string             `bson:"email"`
-	HashedPassword string             `bson:"hashed_password" json:"-"`
-	VehicleLimit   int64              `bson:"vehicle_limit"`
-	CreatedAt      time.Time          `bson:"created_at"`
-	UpdatedAt      time.Time          `bson:"updated_at"`
+	ID                   primitive.ObjectID   `bson:"_id"`
+	Email                string               `bson:"email"`
+	PendingEmail         string               `bson:"pending_email"`
+	HashedPassword       string               `bson:"hashed_password" json:"-"`
+	Plan                 string               `bson:"plan"`
+	NotificationSettings NotificationSettings `bson:"notification_settings"`
+	EmailUnverified      bool                 `bson:"email_unverified"`
+	VerificationSentAt   time.Time            `bson:"verification_sent_at" json:"-"`
+	TwoFactor            TwoFactor            `bson:"two_factor"`
+	OIDCIdentity         OIDCIdentity         `bson:"oidc_identity" json:"-"`
+	Admin                bool                 `bson:"admin"`
+	Disabled             bool                 `bson:"disabled"`
+	DeletionScheduledAt  time.Time            `bson:"deletion_scheduled_at"`
+	CreatedAt            time.Time            `bson:"created_at"`
+	UpdatedAt            time.Time            `bson:"updated_at"`
+}
+
+// TwoFactor holds a user's TOTP second factor. Recovery codes are stored hashed.
+type TwoFactor struct {
+	Enabled            bool     `bson:"enabled"`
+	Secret             string   `bson:"secret" json:"-"`
+	PendingSecret      string   `bson:"pending_secret" json:"-"`
+	RecoveryCodeHashes []string `bson:"recovery_code_hashes" json:"-"`
+	LastUsedStep       int64    `bson:"last_used_step" json:"-"`
+}
+
+// OIDCIdentity links a user to an account at an OpenID Connect provider
+type OIDCIdentity struct {
+	Issuer  string `bson:"issuer"`
+	Subject string `bson:"subject"`
+}
+
+// NotificationSettings controls which channels a user receives notifications on
+type NotificationSettings struct {
+	Email      bool   `bson:"email"`
+	WebhookURL string `bson:"webhook_url"`
 }
 
 // CreateUser writes a new user to the database
@@ -39,6 +71,94 @@ func GetUser(db *Database, email string) (*User, error) {
 	return &user, err
 }
 
+// GetUserByID fetches a user by their ID
+func GetUserByID(db *Database, id primitive.ObjectID) (*User, error) {
+	var user User
+
+	err := userCollection(db).FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+
+	return &user, err
+}
+
+// GetUserByOIDCIdentity fetches the user linked to an OpenID Connect identity
+func GetUserByOIDCIdentity(db *Database, identity OIDCIdentity) (*User, error) {
+	var user User
+
+	query := bson.M{
+		"oidc_identity.issuer":  identity.Issuer,
+		"oidc_identity.subject": identity.Subject,
+	}
+
+	err := userCollection(db).FindOne(ctx, query).Decode(&user)
+
+	return &user, err
+}
+
+// UpdateUser replaces the existing user with the given one
+func UpdateUser(db *Database, user *User) error {
+	user.UpdatedAt = time.Now()
+
+	_, err := userCollection(db).ReplaceOne(
+		ctx,
+		bson.M{"_id": user.ID},
+		user,
+	)
+
+	return err
+}
+
+// SearchUsers fetches a page of users, newest first, whose e-mail address contains query. The total
+// number of matching users is also returned.
+func SearchUsers(db *Database, query string, skip, limit int64) ([]*User, int64, error) {
+	var users []*User
+
+	filter := bson.M{}
+	if query != "" {
+		filter["email"] = primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	}
+
+	total, err := userCollection(db).CountDocuments(ctx, filter)
+	if err != nil {
+		return users, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": -1}).SetSkip(skip).SetLimit(limit)
+
+	cur, err := userCollection(db).Find(ctx, filter, opts)
+	if err != nil {
+		return users, 0, err
+	}
+
+	err = cur.All(ctx, &users)
+
+	return users, total, err
+}
+
+// GetUsersDueForDeletion fetches users whose scheduled account deletion is due
+func GetUsersDueForDeletion(db *Database, timestamp time.Time) ([]*User, error) {
+	var users []*User
+
+	query := bson.M{
+		"deletion_scheduled_at": bson.M{"$ne": time.Time{}, "$lte": timestamp},
+	}
+
+	cur, err := userCollection(db).Find(ctx, query)
+	if err != nil {
+		return users, err
+	}
+
+	err = cur.All(ctx, &users)
+
+	return users, err
+}
+
+// DeleteUser removes a user from the database
+func DeleteUser(db *Database, user *User) error {
+	_, err := userCollection(db).DeleteOne(ctx, bson.M{"_id": user.ID})
+
+	return err
+}
+
 func UserExists(db *Database, email string) bool {
 	query := bson.M{
 		"email": email,
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Vehicle event types
+const (
+	VehicleEventV5CIssued       = "v5c_issued"
+	VehicleEventMarkedForExport = "marked_for_export"
+	VehicleEventTaxStatus       = "tax_status_changed"
+	VehicleEventPlateTransfer   = "plate_transferred"
+	VehicleEventPlateFollowed   = "plate_followed"
+)
+
+// VehicleEvent records a change in a vehicle's registered status spotted during a refresh
+type VehicleEvent struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	VehicleID   primitive.ObjectID `bson:"vehicle_id"`
+	Type        string             `bson:"type"`
+	Description string             `bson:"description"`
+	OldValue    string             `bson:"old_value"`
+	NewValue    string             `bson:"new_value"`
+	OccurredAt  time.Time          `bson:"occurred_at"`
+}
+
+// CreateVehicleEvent writes a new vehicle event to the database
+func CreateVehicleEvent(db *Database, event *VehicleEvent) error {
+	event.ID = primitive.NewObjectID()
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	_, err := vehicleEventCollection(db).InsertOne(ctx, event)
+	return err
+}
+
+// GetVehicleEvents fetches all events for a vehicle, newest first
+func GetVehicleEvents(db *Database, vehicleID primitive.ObjectID) ([]*VehicleEvent, error) {
+	var events []*VehicleEvent
+
+	query := bson.M{
+		"vehicle_id": vehicleID,
+	}
+	opts := options.Find().SetSort(bson.M{"occurred_at": -1})
+
+	cur, err := vehicleEventCollection(db).Find(ctx, query, opts)
+	if err != nil {
+		return events, err
+	}
+
+	err = cur.All(ctx, &events)
+	return events, err
+}
+
+// DeleteVehicleEvents removes every event belonging to a vehicle
+func DeleteVehicleEvents(db *Database, vehicleID primitive.ObjectID) error {
+	_, err := vehicleEventCollection(db).DeleteMany(ctx, bson.M{"vehicle_id": vehicleID})
+
+	return err
+}
+
+func vehicleEventCollection(db *Database) *mongo.Collection {
+	return db.Collection("vehicle_events")
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Levels of access a user can have to a vehicle
+const (
+	VehicleAccessOwner  = "owner"
+	VehicleAccessManage = "manage"
+	VehicleAccessRead   = "read"
+)
+
+// VehicleShare gives another user access to one of a user's personal vehicles. Shares are made to an
+// e-mail address, and until someone verifies that address on an account UserID is zero.
+type VehicleShare struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	VehicleID primitive.ObjectID `bson:"vehicle_id"`
+	OwnerID   primitive.ObjectID `bson:"owner_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"-"`
+	Email     string             `bson:"email"`
+	Access    string             `bson:"access"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// SaveVehicleShare shares a vehicle with a user, or with an e-mail address nobody has registered
+// yet, or changes the access if it is already shared with them
+func SaveVehicleShare(db *Database, share *VehicleShare) error {
+	now := time.Now()
+
+	query := bson.M{
+		"vehicle_id": share.VehicleID,
+	}
+	if share.UserID.IsZero() {
+		query["email"] = share.Email
+		query["user_id"] = primitive.NilObjectID
+	} else {
+		query["user_id"] = share.UserID
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"owner_id":   share.OwnerID,
+			"user_id":    share.UserID,
+			"email":      share.Email,
+			"access":     share.Access,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	return vehicleShareCollection(db).FindOneAndUpdate(ctx, query, update, opts).Decode(share)
+}
+
+// GetVehicleShares fetches everyone a vehicle is shared with
+func GetVehicleShares(db *Database, vehicleID primitive.ObjectID) ([]*VehicleShare, error) {
+	return getVehicleShares(db, bson.M{"vehicle_id": vehicleID})
+}
+
+// GetUserVehicleShares fetches every vehicle share granted to a user
+func GetUserVehicleShares(db *Database, userID primitive.ObjectID) ([]*VehicleShare, error) {
+	return getVehicleShares(db, bson.M{"user_id": userID})
+}
+
+// ClaimVehicleShares gives a user the vehicles shared with their e-mail address before they had a
+// verified account with it
+func ClaimVehicleShares(db *Database, email string, userID primitive.ObjectID) error {
+	_, err := vehicleShareCollection(db).UpdateMany(
+		ctx,
+		bson.M{"email": email, "user_id": primitive.NilObjectID},
+		bson.M{"$set": bson.M{"user_id": userID, "updated_at": time.Now()}},
+	)
+
+	return err
+}
+
+// DeleteVehicleShare revokes one of a vehicle's shares
+func DeleteVehicleShare(db *Database, vehicleID, shareID primitive.ObjectID) error {
+	return deleteVehicleShare(db, bson.M{"_id": shareID, "vehicle_id": vehicleID})
+}
+
+// DeleteUserVehicleShare removes a user's access to a vehicle shared with them
+func DeleteUserVehicleShare(db *Database, vehicleID, userID primitive.ObjectID) error {
+	return deleteVehicleShare(db, bson.M{"vehicle_id": vehicleID, "user_id": userID})
+}
+
+// DeleteVehicleShares revokes every share of a vehicle
+func DeleteVehicleShares(db *Database, vehicleID primitive.ObjectID) error {
+	_, err := vehicleShareCollection(db).DeleteMany(ctx, bson.M{"vehicle_id": vehicleID})
+
+	return err
+}
+
+// DeleteUserVehicleShares removes every vehicle share granted to a user
+func DeleteUserVehicleShares(db *Database, userID primitive.ObjectID) error {
+	_, err := vehicleShareCollection(db).DeleteMany(ctx, bson.M{"user_id": userID})
+
+	return err
+}
+
+// vehicleShareIndexes back looking up the shares of a vehicle and the shares granted to a user
+func vehicleShareIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "vehicle_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}
+}
+
+func vehicleShareCollection(db *Database) *mongo.Collection {
+	return db.Collection("vehicle_shares")
+}
+
+func getVehicleShares(db *Database, query bson.M) ([]*VehicleShare, error) {
+	var shares []*VehicleShare
+
+	cur, err := vehicleShareCollection(db).Find(ctx, query)
+	if err != nil {
+		return shares, err
+	}
+
+	err = cur.All(ctx, &shares)
+
+	return shares, err
+}
+
+func deleteVehicleShare(db *Database, query bson.M) error {
+	result, err := vehicleShareCollection(db).DeleteOne(ctx, query)
+	if err == nil && result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return err
+}
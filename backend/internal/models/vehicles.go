@@ -1,26 +1,63 @@
 package models
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Vehicle is a model of a vehicle inclusive of history that can be written to the database
 type Vehicle struct {
 	ID                 primitive.ObjectID `bson:"_id"`
 	UserID             primitive.ObjectID `bson:"user_id"`
+	OrganisationID     primitive.ObjectID `bson:"organisation_id,omitempty"`
 	RegistrationNumber string             `bson:"registration_number"`
 	Manufacturer       string             `bson:"manufacturer"`
 	Model              string             `bson:"model"`
 	MotDue             time.Time          `bson:"mot_due"`
+	NoMotYet           bool               `bson:"no_mot_yet"`
 	VEDDue             time.Time          `bson:"ved_due"`
 	MOTHistory         []MOTTest          `bson:"mot_history"`
 	CreatedAt          time.Time          `bson:"created_at"`
 	UpdatedAt          time.Time          `bson:"updated_at"`
 	LastFetchedAt      time.Time          `bson:"last_fetched_at"`
+	PlateTransferred   bool               `bson:"plate_transferred"`
+	LastMOTFailed      bool               `bson:"last_mot_failed"`
+	Access             string             `bson:"-"`
+	VehicleSpec        `bson:",inline"`
+}
+
+// VehicleSpec holds the descriptive data published by the DVLA Vehicle Enquiry Service and the
+// DVSA MOT History API. It is replaced wholesale each time the vehicle is refreshed.
+type VehicleSpec struct {
+	DVSAVehicleID            string    `bson:"dvsa_vehicle_id"`
+	Colour                   string    `bson:"colour"`
+	PrimaryColour            string    `bson:"primary_colour"`
+	FuelType                 string    `bson:"fuel_type"`
+	Co2Emissions             int       `bson:"co2_emissions"`
+	EngineCapacity           int       `bson:"engine_capacity"`
+	EngineSize               int       `bson:"engine_size"`
+	TaxStatus                string    `bson:"tax_status"`
+	MotStatus                string    `bson:"mot_status"`
+	EuroStatus               string    `bson:"euro_status"`
+	RealDrivingEmissions     string    `bson:"real_driving_emissions"`
+	MarkedForExport          bool      `bson:"marked_for_export"`
+	Wheelplan                string    `bson:"wheelplan"`
+	TypeApproval             string    `bson:"type_approval"`
+	RevenueWeight            int       `bson:"revenue_weight"`
+	YearOfManufacture        int       `bson:"year_of_manufacture"`
+	MonthOfFirstRegistration string    `bson:"month_of_first_registration"`
+	FirstUsedDate            time.Time `bson:"first_used_date"`
+	RegistrationDate         time.Time `bson:"registration_date"`
+	ManufactureDate          time.Time `bson:"manufacture_date"`
+	DateOfLastV5CIssued      time.Time `bson:"date_of_last_v5c_issued"`
+	ArtEndDate               string    `bson:"art_end_date"`
 }
 
 // MOTTest that can be written to database
@@ -39,27 +76,79 @@ type RfrAndComments struct {
 	Type    string `bson:"type"`
 }
 
+// ApplyDetails copies freshly fetched details onto the vehicle, leaving ownership and
+// bookkeeping fields alone
+func (v *Vehicle) ApplyDetails(details *Vehicle) {
+	v.RegistrationNumber = details.RegistrationNumber
+	v.Manufacturer = details.Manufacturer
+	v.Model = details.Model
+	v.MOTHistory = details.MOTHistory
+	v.MotDue = details.MotDue
+	v.NoMotYet = details.NoMotYet
+	v.VEDDue = details.VEDDue
+	v.LastFetchedAt = details.LastFetchedAt
+	v.VehicleSpec = details.VehicleSpec
+}
+
+// lastMOTFailed is true if the most recently completed MOT test was a failure. It is stored
+// alongside the history so vehicles can be filtered on it.
+func (v *Vehicle) lastMOTFailed() bool {
+	var latest *MOTTest
+	for i, test := range v.MOTHistory {
+		if latest == nil || test.CompletedDate.After(latest.CompletedDate) {
+			latest = &v.MOTHistory[i]
+		}
+	}
+
+	return latest != nil && !latest.Passed
+}
+
 // CreateVehicle writes a Vehicle struct to the database
 func CreateVehicle(db *Database, vehicle *Vehicle) error {
 	vehicle.ID = primitive.NewObjectID()
 	vehicle.CreatedAt = time.Now()
 	vehicle.UpdatedAt = time.Now()
+	vehicle.LastMOTFailed = vehicle.lastMOTFailed()
 
 	_, err := vehicleCollection(db).InsertOne(ctx, vehicle)
 	return err
 }
 
-func GetUserVehicle(db *Database, userID primitive.ObjectID, registrationNumber string) (*Vehicle, error) {
+// GetVehicleByID fetches a vehicle by ID
+func GetVehicleByID(db *Database, vehicleID primitive.ObjectID) (*Vehicle, error) {
 	var vehicle Vehicle
 
-	query := bson.M{
-		"registration_number": registrationNumber,
-		"user_id":             userID,
+	err := vehicleCollection(db).FindOne(ctx, bson.M{"_id": vehicleID}).Decode(&vehicle)
+
+	return &vehicle, err
+}
+
+// GetUserVehicle fetches one of a user's personal vehicles, or a vehicle shared with them, by
+// registration number. Access is set to the user's level of access.
+func GetUserVehicle(db *Database, userID primitive.ObjectID, registrationNumber string) (*Vehicle, error) {
+	vehicle, err := getVehicle(db, userVehiclesQuery(userID), registrationNumber)
+	if err == nil {
+		vehicle.Access = VehicleAccessOwner
+		return vehicle, nil
+	} else if err != mongo.ErrNoDocuments {
+		return vehicle, err
 	}
 
-	err := vehicleCollection(db).FindOne(ctx, query).Decode(&vehicle)
+	shared, err := getSharedVehicles(db, userID, bson.M{"registration_number": registrationNumber})
+	if err != nil {
+		return vehicle, err
+	}
 
-	return &vehicle, err
+	if len(shared) == 0 {
+		return vehicle, mongo.ErrNoDocuments
+	}
+
+	return shared[0], nil
+}
+
+// GetOrganisationVehicle fetches one of an organisation's vehicles by registration number
+func GetOrganisationVehicle(db *Database, organisationID primitive.ObjectID, registrationNumber string) (*Vehicle, error) {
+	return getVehicle(db, organisationVehiclesQuery(organisationID), registrationNumber)
 }
 
 // DeleteVehicle deletes a vehicle from the database
@@ -69,38 +158,110 @@ func DeleteVehicle(db *Database, vehicle *Vehicle) error {
 	return err
 }
 
-// GetUserVehicles fetches all vehicles for the given user ID
-func GetUserVehicles(db *Database, userID primitive.ObjectID) ([]*Vehicle, error) {
-	query := bson.M{
-		"user_id": userID,
+// PurgeVehicle deletes a vehicle along with everything stored against it: its shares, share links
+// and events
+func PurgeVehicle(db *Database, vehicle *Vehicle) error {
+	err := DeleteVehicle(db, vehicle)
+	if err != nil {
+		return err
 	}
 
-	return getVehicles(db, query)
+	err = DeleteVehicleShares(db, vehicle.ID)
+	if err != nil {
+		return err
+	}
+
+	err = DeleteVehicleShareLinks(db, vehicle.ID)
+	if err != nil {
+		return err
+	}
+
+	return DeleteVehicleEvents(db, vehicle.ID)
 }
 
-func UserVehicleExists(db *Database, userID primitive.ObjectID, registrationNumber string) bool {
-	query := bson.M{
-		"registration_number": registrationNumber,
-		"user_id":             userID,
+// GetUserVehicles fetches all personal vehicles for the given user ID, followed by any vehicles
+// shared with them. Access is set to the user's level of access to each.
+func GetUserVehicles(db *Database, userID primitive.ObjectID) ([]*Vehicle, error) {
+	vehicles, err := getVehicles(db, userVehiclesQuery(userID))
+	if err != nil {
+		return vehicles, err
 	}
 
-	count, err := vehicleCollection(db).CountDocuments(ctx, query)
+	for _, vehicle := range vehicles {
+		vehicle.Access = VehicleAccessOwner
+	}
 
-	return err == nil && count > 0
+	shared, err := getSharedVehicles(db, userID, bson.M{})
+	if err != nil {
+		return vehicles, err
+	}
+
+	return append(vehicles, shared...), nil
 }
 
-func UserVehicleCount(db *Database, userID primitive.ObjectID) int64 {
-	query := bson.M{
-		"user_id": userID,
+// GetUserVehiclePage fetches a page of a user's personal vehicles and the vehicles shared with them,
+// filtered and ordered by listQuery. Access is set to the user's level of access to each.
+func GetUserVehiclePage(db *Database, userID primitive.ObjectID, listQuery VehicleListQuery) (*VehiclePage, error) {
+	shares, err := GetUserVehicleShares(db, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	count, err := vehicleCollection(db).CountDocuments(ctx, query)
+	access := make(map[primitive.ObjectID]string)
+	vehicleIDs := []primitive.ObjectID{}
+	for _, share := range shares {
+		access[share.VehicleID] = share.Access
+		vehicleIDs = append(vehicleIDs, share.VehicleID)
+	}
+
+	query := bson.M{
+		"$or": bson.A{
+			userVehiclesQuery(userID),
+			bson.M{"_id": bson.M{"$in": vehicleIDs}},
+		},
+	}
 
+	page, err := getVehiclePage(db, query, listQuery)
 	if err != nil {
-		return 0
+		return nil, err
 	}
 
-	return count
+	for _, vehicle := range page.Vehicles {
+		if sharedAccess, ok := access[vehicle.ID]; ok {
+			vehicle.Access = sharedAccess
+		} else {
+			vehicle.Access = VehicleAccessOwner
+		}
+	}
+
+	return page, nil
+}
+
+// GetOrganisationVehiclePage fetches a page of an organisation's vehicles, filtered and ordered by
+// listQuery
+func GetOrganisationVehiclePage(db *Database, organisationID primitive.ObjectID, listQuery VehicleListQuery) (*VehiclePage, error) {
+	return getVehiclePage(db, organisationVehiclesQuery(organisationID), listQuery)
+}
+
+// GetOrganisationVehicles fetches all vehicles belonging to an organisation
+func GetOrganisationVehicles(db *Database, organisationID primitive.ObjectID) ([]*Vehicle, error) {
+	return getVehicles(db, organisationVehiclesQuery(organisationID))
+}
+
+func UserVehicleExists(db *Database, userID primitive.ObjectID, registrationNumber string) bool {
+	return vehicleExists(db, userVehiclesQuery(userID), registrationNumber)
+}
+
+func OrganisationVehicleExists(db *Database, organisationID primitive.ObjectID, registrationNumber string) bool {
+	return vehicleExists(db, organisationVehiclesQuery(organisationID), registrationNumber)
+}
+
+func UserVehicleCount(db *Database, userID primitive.ObjectID) int64 {
+	return vehicleCount(db, userVehiclesQuery(userID))
+}
+
+func OrganisationVehicleCount(db *Database, organisationID primitive.ObjectID) int64 {
+	return vehicleCount(db, organisationVehiclesQuery(organisationID))
 }
 
 // GetVehiclesUpdatedBefore fetches any vehicle that has a LastRemotePull value less than timestamp
@@ -112,8 +273,24 @@ func GetVehiclesUpdatedBefore(db *Database, timestamp time.Time) ([]*Vehicle, er
 	return getVehicles(db, query)
 }
 
+// ScheduleVehicleRefresh marks a vehicle as stale so the background task fetches it on its next run
+func ScheduleVehicleRefresh(db *Database, vehicleID primitive.ObjectID) error {
+	result, err := vehicleCollection(db).UpdateOne(
+		ctx,
+		bson.M{"_id": vehicleID},
+		bson.M{"$set": bson.M{"last_fetched_at": time.Time{}}},
+	)
+	if err == nil && result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return err
+}
+
 // UpdateVehicle replaces the existing vehicle with a brand new one
 func UpdateVehicle(db *Database, v *Vehicle) error {
+	v.LastMOTFailed = v.lastMOTFailed()
+
 	_, err := vehicleCollection(db).ReplaceOne(
 		ctx,
 		bson.M{"_id": primitive.ObjectID(v.ID)},
@@ -127,6 +304,77 @@ func vehicleCollection(db *Database) *mongo.Collection {
 	return db.Collection("vehicles")
 }
 
+// userVehiclesQuery matches a user's personal vehicles. Vehicles a user added to an organisation
+// also carry their user ID, so are excluded.
+func userVehiclesQuery(userID primitive.ObjectID) bson.M {
+	return bson.M{
+		"user_id":         userID,
+		"organisation_id": bson.M{"$exists": false},
+	}
+}
+
+func organisationVehiclesQuery(organisationID primitive.ObjectID) bson.M {
+	return bson.M{
+		"organisation_id": organisationID,
+	}
+}
+
+// getSharedVehicles fetches the vehicles shared with a user which also match query
+func getSharedVehicles(db *Database, userID primitive.ObjectID, query bson.M) ([]*Vehicle, error) {
+	shares, err := GetUserVehicleShares(db, userID)
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+
+	access := make(map[primitive.ObjectID]string)
+	var vehicleIDs []primitive.ObjectID
+	for _, share := range shares {
+		access[share.VehicleID] = share.Access
+		vehicleIDs = append(vehicleIDs, share.VehicleID)
+	}
+
+	query["_id"] = bson.M{"$in": vehicleIDs}
+
+	vehicles, err := getVehicles(db, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vehicle := range vehicles {
+		vehicle.Access = access[vehicle.ID]
+	}
+
+	return vehicles, nil
+}
+
+func getVehicle(db *Database, query bson.M, registrationNumber string) (*Vehicle, error) {
+	var vehicle Vehicle
+
+	query["registration_number"] = registrationNumber
+
+	err := vehicleCollection(db).FindOne(ctx, query).Decode(&vehicle)
+
+	return &vehicle, err
+}
+
+func vehicleExists(db *Database, query bson.M, registrationNumber string) bool {
+	query["registration_number"] = registrationNumber
+
+	count, err := vehicleCollection(db).CountDocuments(ctx, query)
+
+	return err == nil && count > 0
+}
+
+func vehicleCount(db *Database, query bson.M) int64 {
+	count, err := vehicleCollection(db).CountDocuments(ctx, query)
+
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
 func getVehicles(db *Database, query bson.M) ([]*Vehicle, error) {
 	var vehicles []*Vehicle
 
@@ -142,3 +390,220 @@ func getVehicles(db *Database, query bson.M) ([]*Vehicle, error) {
 
 	return vehicles, nil
 }
+
+// Orders a list of vehicles can be sorted in
+const (
+	VehicleSortRegistration = "registration"
+	VehicleSortMotDue       = "mot_due"
+	VehicleSortVEDDue       = "ved_due"
+	VehicleSortManufacturer = "manufacturer"
+	VehicleSortCreated      = "created"
+)
+
+// vehicleSortFields maps each sort order to the field it sorts on
+var vehicleSortFields = map[string]string{
+	VehicleSortRegistration: "registration_number",
+	VehicleSortMotDue:       "mot_due",
+	VehicleSortVEDDue:       "ved_due",
+	VehicleSortManufacturer: "manufacturer",
+	VehicleSortCreated:      "created_at",
+}
+
+// ErrInvalidVehicleCursor is returned when a page is requested after a cursor which wasn't issued
+// for the same sort order
+var ErrInvalidVehicleCursor = errors.New("Invalid cursor")
+
+// ValidVehicleSort returns true if vehicles can be sorted by sort
+func ValidVehicleSort(sort string) bool {
+	_, ok := vehicleSortFields[sort]
+	return ok
+}
+
+// VehicleListQuery filters and orders a list of vehicles. Zero values don't filter.
+type VehicleListQuery struct {
+	Sort          string
+	Descending    bool
+	After         string
+	Limit         int64
+	MotDueBefore  time.Time
+	TaxExpired    bool
+	FailedLastMOT bool
+	Manufacturer  string
+}
+
+// VehiclePage is one page of a list of vehicles. NextCursor is empty on the last page.
+type VehiclePage struct {
+	Vehicles   []*Vehicle
+	NextCursor string
+}
+
+// vehicleCursor marks the position of the last vehicle on a page: its value of the field being
+// sorted on, and its ID to break ties
+type vehicleCursor struct {
+	Sort  string             `bson:"sort"`
+	Value interface{}        `bson:"value"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+func encodeVehicleCursor(sort string, vehicle *Vehicle) (string, error) {
+	var value interface{}
+	switch sort {
+	case VehicleSortRegistration:
+		value = vehicle.RegistrationNumber
+	case VehicleSortMotDue:
+		value = vehicle.MotDue
+	case VehicleSortVEDDue:
+		value = vehicle.VEDDue
+	case VehicleSortManufacturer:
+		value = vehicle.Manufacturer
+	case VehicleSortCreated:
+		value = vehicle.CreatedAt
+	}
+
+	data, err := bson.Marshal(vehicleCursor{Sort: sort, Value: value, ID: vehicle.ID})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeVehicleCursor reads a cursor issued by encodeVehicleCursor. Cursors come from the client,
+// so the value is only accepted as the type the sort field holds, never as a document which could
+// change the meaning of the query it ends up in.
+func decodeVehicleCursor(sort, encoded string) (*vehicleCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidVehicleCursor
+	}
+
+	var raw struct {
+		Sort  string             `bson:"sort"`
+		Value bson.RawValue      `bson:"value"`
+		ID    primitive.ObjectID `bson:"id"`
+	}
+	err = bson.Unmarshal(data, &raw)
+	if err != nil || raw.Sort != sort {
+		return nil, ErrInvalidVehicleCursor
+	}
+
+	cursor := vehicleCursor{Sort: raw.Sort, ID: raw.ID}
+	switch sort {
+	case VehicleSortRegistration, VehicleSortManufacturer:
+		value, ok := raw.Value.StringValueOK()
+		if !ok {
+			return nil, ErrInvalidVehicleCursor
+		}
+		cursor.Value = value
+	case VehicleSortMotDue, VehicleSortVEDDue, VehicleSortCreated:
+		value, ok := raw.Value.TimeOK()
+		if !ok {
+			return nil, ErrInvalidVehicleCursor
+		}
+		cursor.Value = value
+	default:
+		return nil, ErrInvalidVehicleCursor
+	}
+
+	return &cursor, nil
+}
+
+// vehicleIndexes back the vehicle list, which sorts on one field after filtering by owner, and
+// the background refresh
+func vehicleIndexes() []mongo.IndexModel {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "last_fetched_at", Value: 1}}},
+	}
+
+	for _, owner := range []string{"user_id", "organisation_id"} {
+		for _, field := range vehicleSortFields {
+			indexes = append(indexes, mongo.IndexModel{
+				Keys: bson.D{{Key: owner, Value: 1}, {Key: field, Value: 1}, {Key: "_id", Value: 1}},
+			})
+		}
+	}
+
+	return indexes
+}
+
+// getVehiclePage fetches a page of the vehicles matching query, with the list query's filters,
+// ordering and cursor applied. Paging is by position rather than offset, so vehicles added or
+// removed between pages don't cause others to be skipped or repeated.
+func getVehiclePage(db *Database, query bson.M, listQuery VehicleListQuery) (*VehiclePage, error) {
+	sort := listQuery.Sort
+	if sort == "" {
+		sort = VehicleSortRegistration
+	}
+
+	field, ok := vehicleSortFields[sort]
+	if !ok {
+		return nil, fmt.Errorf("Cannot sort vehicles by %q", sort)
+	}
+
+	conditions := bson.A{query}
+
+	if !listQuery.MotDueBefore.IsZero() {
+		conditions = append(conditions, bson.M{"mot_due": bson.M{"$gt": time.Time{}, "$lte": listQuery.MotDueBefore}})
+	}
+
+	if listQuery.TaxExpired {
+		conditions = append(conditions, bson.M{"ved_due": bson.M{"$gt": time.Time{}, "$lt": time.Now()}})
+	}
+
+	if listQuery.FailedLastMOT {
+		conditions = append(conditions, bson.M{"last_mot_failed": true})
+	}
+
+	if listQuery.Manufacturer != "" {
+		conditions = append(conditions, bson.M{"manufacturer": listQuery.Manufacturer})
+	}
+
+	direction, comparison := 1, "$gt"
+	if listQuery.Descending {
+		direction, comparison = -1, "$lt"
+	}
+
+	if listQuery.After != "" {
+		cursor, err := decodeVehicleCursor(sort, listQuery.After)
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, bson.M{
+			"$or": bson.A{
+				bson.M{field: bson.M{comparison: cursor.Value}},
+				bson.M{field: cursor.Value, "_id": bson.M{comparison: cursor.ID}},
+			},
+		})
+	}
+
+	// One extra vehicle is fetched to find out whether there is another page
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(listQuery.Limit + 1)
+
+	var vehicles []*Vehicle
+
+	cur, err := vehicleCollection(db).Find(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.All(ctx, &vehicles)
+	if err != nil {
+		return nil, err
+	}
+
+	page := VehiclePage{Vehicles: vehicles}
+
+	if int64(len(vehicles)) > listQuery.Limit {
+		page.Vehicles = vehicles[:listQuery.Limit]
+
+		page.NextCursor, err = encodeVehicleCursor(sort, page.Vehicles[len(page.Vehicles)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestVehicleCursorRoundTrip(t *testing.T) {
+	vehicle := Vehicle{
+		ID:                 primitive.NewObjectID(),
+		RegistrationNumber: "AB12CDE",
+		Manufacturer:       "FORD",
+		MotDue:             time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC),
+		VEDDue:             time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:          time.Date(2020, time.May, 1, 12, 30, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		sort  string
+		value interface{}
+	}{
+		{sort: VehicleSortRegistration, value: vehicle.RegistrationNumber},
+		{sort: VehicleSortManufacturer, value: vehicle.Manufacturer},
+		{sort: VehicleSortMotDue, value: vehicle.MotDue},
+		{sort: VehicleSortVEDDue, value: vehicle.VEDDue},
+		{sort: VehicleSortCreated, value: vehicle.CreatedAt},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sort, func(t *testing.T) {
+			encoded, err := encodeVehicleCursor(tc.sort, &vehicle)
+			if err != nil {
+				t.Fatalf("Expected no error but got '%s'", err)
+			}
+
+			cursor, err := decodeVehicleCursor(tc.sort, encoded)
+			if err != nil {
+				t.Fatalf("Expected no error but got '%s'", err)
+			}
+
+			if cursor.ID != vehicle.ID {
+				t.Errorf("Expected ID %s but got %s", vehicle.ID.Hex(), cursor.ID.Hex())
+			}
+
+			switch want := tc.value.(type) {
+			case time.Time:
+				got, ok := cursor.Value.(time.Time)
+				if !ok || !got.Equal(want) {
+					t.Errorf("Expected value %v but got %v", want, cursor.Value)
+				}
+			default:
+				if cursor.Value != want {
+					t.Errorf("Expected value %v but got %v", want, cursor.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeVehicleCursorRejects(t *testing.T) {
+	vehicleID := primitive.NewObjectID()
+
+	testCases := []struct {
+		name    string
+		sort    string
+		encoded string
+		doc     bson.M
+	}{
+		{name: "not base64", sort: VehicleSortRegistration, encoded: "!!!"},
+		{name: "not bson", sort: VehicleSortRegistration, encoded: base64.RawURLEncoding.EncodeToString([]byte("cursor"))},
+		{name: "other sort", sort: VehicleSortManufacturer, doc: bson.M{"sort": VehicleSortRegistration, "value": "AB12CDE", "id": vehicleID}},
+		{name: "unknown sort", sort: "colour", doc: bson.M{"sort": "colour", "value": "RED", "id": vehicleID}},
+		{name: "operator document", sort: VehicleSortRegistration, doc: bson.M{"sort": VehicleSortRegistration, "value": bson.M{"$ne": ""}, "id": vehicleID}},
+		{name: "number for string sort", sort: VehicleSortManufacturer, doc: bson.M{"sort": VehicleSortManufacturer, "value": 1, "id": vehicleID}},
+		{name: "string for time sort", sort: VehicleSortMotDue, doc: bson.M{"sort": VehicleSortMotDue, "value": "2021-03-01", "id": vehicleID}},
+		{name: "operator document for time sort", sort: VehicleSortCreated, doc: bson.M{"sort": VehicleSortCreated, "value": bson.M{"$gt": time.Time{}}, "id": vehicleID}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := tc.encoded
+			if tc.doc != nil {
+				data, err := bson.Marshal(tc.doc)
+				if err != nil {
+					t.Fatalf("Expected no error but got '%s'", err)
+				}
+				encoded = base64.RawURLEncoding.EncodeToString(data)
+			}
+
+			if _, err := decodeVehicleCursor(tc.sort, encoded); err != ErrInvalidVehicleCursor {
+				t.Errorf("Expected error '%s' but got '%v'", ErrInvalidVehicleCursor, err)
+			}
+		})
+	}
+}
+
+func TestLastMOTFailed(t *testing.T) {
+	older := time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		history []MOTTest
+		failed  bool
+	}{
+		{name: "no history", history: nil, failed: false},
+		{name: "single pass", history: []MOTTest{{Passed: true, CompletedDate: newer}}, failed: false},
+		{name: "single fail", history: []MOTTest{{Passed: false, CompletedDate: newer}}, failed: true},
+		{name: "fail then pass", history: []MOTTest{{Passed: false, CompletedDate: older}, {Passed: true, CompletedDate: newer}}, failed: false},
+		{name: "pass then fail", history: []MOTTest{{Passed: true, CompletedDate: older}, {Passed: false, CompletedDate: newer}}, failed: true},
+		{name: "newest first", history: []MOTTest{{Passed: false, CompletedDate: newer}, {Passed: true, CompletedDate: older}}, failed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vehicle := Vehicle{MOTHistory: tc.history}
+			if got := vehicle.lastMOTFailed(); got != tc.failed {
+				t.Errorf("Expected last MOT failed %t but got %t", tc.failed, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package mothistoryapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -12,6 +13,10 @@ const (
 	defaultHost = "https://beta.check-mot.service.gov.uk"
 )
 
+// ErrNoHistory is returned when the API holds no MOT history for a vehicle, which
+// is normal for vehicles less than three years old
+var ErrNoHistory = errors.New("No MOT history found for vehicle")
+
 // Client is an API Client for MOT History API
 type Client struct {
 	apiKey   string
@@ -54,6 +59,10 @@ func (c *Client) GetVehicleHistory(registrationNumber string) (*Vehicle, error)
 		return nil, err
 	}
 
+	if len(res) == 0 {
+		return nil, ErrNoHistory
+	}
+
 	return &res[0], nil
 }
 
@@ -68,6 +77,10 @@ func (c *Client) sendRequest(req *http.Request, vehicles *Vehicles) error {
 
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNoHistory
+	}
+
 	if res.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(res.Body)
 		return fmt.Errorf("HTTP %d: %s", res.StatusCode, body)
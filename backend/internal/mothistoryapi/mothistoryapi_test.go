@@ -32,7 +32,19 @@ func TestGetVehicleHistory(t *testing.T) {
 		err      error
 	}{
 		{
-			name: "invalid url returns error",
+			name: "server error returns error",
+			request: request{
+				Path: "/vehicle-enquiry/v1/vehicles?registration=P239FWP",
+			},
+			response: &response{
+				code: 500,
+				body: `internal_error`,
+			},
+			err:     errors.New("HTTP 500: internal_error"),
+			vehicle: nil,
+		},
+		{
+			name: "not found returns no history",
 			request: request{
 				Path: "/vehicle-enquiry/v1/vehicles?registration=P239FWP",
 			},
@@ -40,7 +52,19 @@ func TestGetVehicleHistory(t *testing.T) {
 				code: 404,
 				body: `not_found`,
 			},
-			err:     errors.New("HTTP 404: not_found"),
+			err:     ErrNoHistory,
+			vehicle: nil,
+		},
+		{
+			name: "empty list returns no history",
+			request: request{
+				Path: "/vehicle-enquiry/v1/vehicles?registration=P239FWP",
+			},
+			response: &response{
+				code: 200,
+				body: `[]`,
+			},
+			err:     ErrNoHistory,
 			vehicle: nil,
 		},
 		{
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/darkphnx/vehiclemanager/internal/mailer"
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/plans"
+)
+
+// Notification is a message to be delivered to a user over their chosen channels
+type Notification struct {
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications over every channel a user has enabled
+type Notifier struct {
+	mailer *mailer.Mailer
+	client *http.Client
+}
+
+// NewNotifier returns a new Notifier which sends e-mail through the given mailer
+func NewNotifier(m *mailer.Mailer) *Notifier {
+	return &Notifier{
+		mailer: m,
+		client: newWebhookClient(),
+	}
+}
+
+// Notify sends the notification to each of the user's enabled channels that their plan allows.
+// Every channel is attempted and the first error encountered is returned.
+func (n *Notifier) Notify(user *models.User, notification Notification) error {
+	var firstErr error
+
+	plan := plans.Get(user.Plan)
+
+	// Mail is withheld until the user has proven they own the address
+	if user.NotificationSettings.Email && !user.EmailUnverified && plan.AllowsChannel(plans.ChannelEmail) {
+		err := n.mailer.Send(user.Email, notification.Subject, notification.Body)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if user.NotificationSettings.WebhookURL != "" && plan.AllowsChannel(plans.ChannelWebhook) {
+		err := n.sendWebhook(user.NotificationSettings.WebhookURL, notification)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (n *Notifier) sendWebhook(url string, notification Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	res, err := n.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook returned HTTP %d", res.StatusCode)
+	}
+
+	return nil
+}
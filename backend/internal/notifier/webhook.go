@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookURLInvalid is returned for a webhook URL which isn't an absolute https URL
+var ErrWebhookURLInvalid = errors.New("Webhook URL must be a valid https URL")
+
+// ErrWebhookAddressNotAllowed is returned when a webhook host resolves to an address on the local
+// machine or a private network, which must not be reachable by users through webhooks
+var ErrWebhookAddressNotAllowed = errors.New("Webhook URL must resolve to a public address")
+
+// nonPublicNetworks are the ranges webhooks may not be delivered to, in addition to loopback,
+// link-local, multicast and unspecified addresses
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+// ValidateWebhookURL checks that a webhook URL is an https URL whose host only resolves to public
+// addresses. Addresses are checked again when connecting, as they may have changed since.
+func ValidateWebhookURL(rawURL string) error {
+	webhookURL, err := url.Parse(rawURL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Hostname() == "" {
+		return ErrWebhookURLInvalid
+	}
+
+	ips, err := net.LookupIP(webhookURL.Hostname())
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("Webhook URL host %s could not be found", webhookURL.Hostname())
+	}
+
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// newWebhookClient returns an HTTP client which refuses to connect to non-public addresses. The
+// check is made on the address actually being dialled, so it also covers redirects and hosts which
+// resolve differently by the time the webhook is sent.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || !publicIP(ip) {
+				return ErrWebhookAddressNotAllowed
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+}
+
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
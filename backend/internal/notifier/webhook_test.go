@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPublicIP(t *testing.T) {
+	testCases := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "93.184.216.34", public: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{ip: "127.0.0.1", public: false},
+		{ip: "::1", public: false},
+		{ip: "0.0.0.0", public: false},
+		{ip: "::", public: false},
+		{ip: "10.1.2.3", public: false},
+		{ip: "172.16.0.1", public: false},
+		{ip: "172.32.0.1", public: true},
+		{ip: "192.168.1.1", public: false},
+		{ip: "100.64.0.1", public: false},
+		{ip: "169.254.169.254", public: false},
+		{ip: "fe80::1", public: false},
+		{ip: "fd00::1", public: false},
+		{ip: "::ffff:127.0.0.1", public: false},
+		{ip: "::ffff:10.0.0.1", public: false},
+		{ip: "224.0.0.1", public: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			if got := publicIP(net.ParseIP(tc.ip)); got != tc.public {
+				t.Errorf("Expected public %t but got %t", tc.public, got)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	testCases := []struct {
+		url string
+		err error
+	}{
+		{url: "http://example.com/hook", err: ErrWebhookURLInvalid},
+		{url: "https://", err: ErrWebhookURLInvalid},
+		{url: "not a url", err: ErrWebhookURLInvalid},
+		{url: "https://127.0.0.1/hook", err: ErrWebhookAddressNotAllowed},
+		{url: "https://169.254.169.254/latest/meta-data", err: ErrWebhookAddressNotAllowed},
+		{url: "https://[::1]:8443/hook", err: ErrWebhookAddressNotAllowed},
+		{url: "https://localhost/hook", err: ErrWebhookAddressNotAllowed},
+		{url: "https://93.184.216.34/hook", err: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			if err := ValidateWebhookURL(tc.url); err != tc.err {
+				t.Errorf("Expected error '%v' but got '%v'", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Provider is an OpenID Connect identity provider used for the authorization code flow with PKCE.
+// The provider's configuration and keys are discovered from its issuer URL on first use.
+type Provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	client       *http.Client
+
+	mu     sync.Mutex
+	config *discoveryDocument
+	keys   map[string]interface{}
+}
+
+// Claims are the verified details of the user from an ID token
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type idTokenClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	jwt.StandardClaims
+}
+
+// NewProvider returns a new Provider
+func NewProvider(issuer, clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// NewCodeVerifier returns a random PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewState returns a random value suitable for the state or nonce parameters
+func NewState() (string, error) {
+	return randomString(16)
+}
+
+// CodeChallenge returns the S256 PKCE challenge for a verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL to send the user to in order to sign in with the provider
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	config, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.clientID)
+	params.Set("redirect_uri", p.redirectURL)
+	params.Set("scope", "openid email")
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(config.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return config.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange swaps an authorization code for tokens and returns the verified claims from the ID
+// token, which must carry the nonce sent in the authorization request
+func (p *Provider) Exchange(code, codeVerifier, nonce string) (*Claims, error) {
+	config, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("client_id", p.clientID)
+	form.Set("client_secret", p.clientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	res, err := p.client.PostForm(config.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode, body)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&tokenResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("Token response did not include an ID token")
+	}
+
+	return p.verifyIDToken(tokenResponse.IDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(idToken, nonce string) (*Claims, error) {
+	config, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseWithClaims(idToken, &idTokenClaims{}, p.keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*idTokenClaims)
+	if !ok {
+		return nil, errors.New("Couldn't parse ID token")
+	}
+
+	if claims.Issuer != config.Issuer {
+		return nil, fmt.Errorf("Unexpected ID token issuer %s", claims.Issuer)
+	}
+
+	if !claims.VerifyAudience(p.clientID, true) {
+		return nil, errors.New("ID token was not issued for this client")
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	return &Claims{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+	}, nil
+}
+
+// keyfunc finds the provider's key for an ID token, refetching the key set once if the key isn't
+// known in case the provider has rotated its keys
+func (p *Provider) keyfunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+	default:
+		return nil, fmt.Errorf("Unexpected signing method %s", token.Method.Alg())
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	err := p.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok = p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("Unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+func (p *Provider) discover() (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.config != nil {
+		return p.config, nil
+	}
+
+	var config discoveryDocument
+	err := p.getJSON(p.issuer+"/.well-known/openid-configuration", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(config.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("Discovered issuer %s does not match %s", config.Issuer, p.issuer)
+	}
+
+	p.config = &config
+	return p.config, nil
+}
+
+func (p *Provider) fetchKeys() error {
+	config, err := p.discover()
+	if err != nil {
+		return err
+	}
+
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	err = p.getJSON(config.JwksURI, &jwks)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := decodeBigInt(jwk.N)
+			e, errE := decodeBigInt(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			if jwk.Curve != "P-256" {
+				continue
+			}
+			x, errX := decodeBigInt(jwk.X)
+			y, errY := decodeBigInt(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *Provider) getJSON(url string, v interface{}) error {
+	res, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("HTTP %d: %s", res.StatusCode, body)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
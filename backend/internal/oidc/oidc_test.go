@@ -0,0 +1,152 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mockProvider is a minimal OpenID Connect provider which issues an ID token for a single
+// authorization code, checking the PKCE verifier against the challenge it was given
+type mockProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mp := &mockProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mp.server.URL,
+			"authorization_endpoint": mp.server.URL + "/authorize",
+			"token_endpoint":         mp.server.URL + "/token",
+			"jwks_uri":               mp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || CodeChallenge(r.Form.Get("code_verifier")) != mp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, mp.claims)
+		token.Header["kid"] = "mock"
+		idToken, _ := token.SignedString(key)
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+
+	mp.server = httptest.NewServer(mux)
+	return mp
+}
+
+func TestExchange(t *testing.T) {
+	mp := newMockProvider(t)
+	defer mp.server.Close()
+
+	testCases := []struct {
+		name     string
+		code     string
+		nonce    string
+		claims   jwt.MapClaims
+		verified bool
+		err      bool
+	}{
+		{
+			name:     "valid login",
+			code:     "good-code",
+			nonce:    "nonce",
+			claims:   jwt.MapClaims{"sub": "123", "email": "user@example.com", "email_verified": true, "nonce": "nonce", "aud": "client"},
+			verified: true,
+		},
+		{
+			name:   "unverified email",
+			code:   "good-code",
+			nonce:  "nonce",
+			claims: jwt.MapClaims{"sub": "123", "email": "user@example.com", "email_verified": false, "nonce": "nonce", "aud": "client"},
+		},
+		{
+			name:   "wrong nonce",
+			code:   "good-code",
+			nonce:  "nonce",
+			claims: jwt.MapClaims{"sub": "123", "nonce": "other", "aud": "client"},
+			err:    true,
+		},
+		{
+			name:   "wrong audience",
+			code:   "good-code",
+			nonce:  "nonce",
+			claims: jwt.MapClaims{"sub": "123", "nonce": "nonce", "aud": "someone-else"},
+			err:    true,
+		},
+		{
+			name:  "bad code",
+			code:  "bad-code",
+			nonce: "nonce",
+			err:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := NewProvider(mp.server.URL, "client", "secret", "http://localhost/callback")
+
+			verifier, _ := NewCodeVerifier()
+			authURL, err := provider.AuthCodeURL("state", tc.nonce, verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, _ := url.Parse(authURL)
+			mp.challenge = parsed.Query().Get("code_challenge")
+			if parsed.Query().Get("code_challenge_method") != "S256" || !strings.HasSuffix(parsed.Path, "/authorize") {
+				t.Errorf("Unexpected authorization URL %s", authURL)
+			}
+
+			mp.claims = jwt.MapClaims{"iss": mp.server.URL, "exp": time.Now().Add(time.Minute).Unix()}
+			for k, v := range tc.claims {
+				mp.claims[k] = v
+			}
+
+			claims, err := provider.Exchange(tc.code, verifier, tc.nonce)
+			if (err != nil) != tc.err {
+				t.Fatalf("Expected error %t but got '%v'", tc.err, err)
+			}
+
+			if err == nil && (claims.Subject != "123" || claims.EmailVerified != tc.verified) {
+				t.Errorf("Unexpected claims %+v", claims)
+			}
+		})
+	}
+}
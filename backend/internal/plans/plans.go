@@ -0,0 +1,146 @@
+package plans
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Plan names
+const (
+	Free     = "free"
+	Personal = "personal"
+	Fleet    = "fleet"
+)
+
+// Reminder channels a plan may allow
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// ErrUnknownPlan is returned when looking up a plan which doesn't exist
+var ErrUnknownPlan = errors.New("Plan must be one of free, personal or fleet")
+
+// ErrAPITokensNotIncluded is returned when a plan without API access is used to create or use an
+// API token
+var ErrAPITokensNotIncluded = errors.New("API tokens are not included in your plan")
+
+// Plan sets what a user is entitled to. Billing is handled elsewhere, if at all; a plan only
+// describes the limits that apply once a user is on it.
+type Plan struct {
+	Name                 string
+	VehicleLimit         int64
+	OrganisationLimit    int64
+	ReminderChannels     []string
+	APITokens            bool
+	RefreshIntervalHours int64
+}
+
+var plans = []*Plan{
+	{Name: Free, VehicleLimit: 5, OrganisationLimit: 1, ReminderChannels: []string{ChannelEmail}, APITokens: false, RefreshIntervalHours: 24},
+	{Name: Personal, VehicleLimit: 25, OrganisationLimit: 3, ReminderChannels: []string{ChannelEmail, ChannelWebhook}, APITokens: true, RefreshIntervalHours: 6},
+	{Name: Fleet, VehicleLimit: 500, OrganisationLimit: 10, ReminderChannels: []string{ChannelEmail, ChannelWebhook}, APITokens: true, RefreshIntervalHours: 1},
+}
+
+// All returns every plan, from least to most generous
+func All() []*Plan {
+	return plans
+}
+
+// Lookup finds a plan by name
+func Lookup(name string) (*Plan, error) {
+	for _, plan := range plans {
+		if plan.Name == name {
+			return plan, nil
+		}
+	}
+
+	return nil, ErrUnknownPlan
+}
+
+// Get returns the named plan. Users who have never been assigned a plan, or whose plan no longer
+// exists, are on the free plan.
+func Get(name string) *Plan {
+	plan, err := Lookup(name)
+	if err != nil {
+		return plans[0]
+	}
+
+	return plan
+}
+
+// MinRefreshInterval is the shortest refresh interval of any plan, and so the most often any
+// vehicle can be due a refresh
+func MinRefreshInterval() time.Duration {
+	min := plans[0].RefreshInterval()
+	for _, plan := range plans {
+		if plan.RefreshInterval() < min {
+			min = plan.RefreshInterval()
+		}
+	}
+
+	return min
+}
+
+// CheckVehicleCount returns an error if the plan doesn't allow another vehicle on top of count. A
+// limit of zero is unlimited.
+func (p *Plan) CheckVehicleCount(count int64) error {
+	if p.VehicleLimit != 0 && count >= p.VehicleLimit {
+		return fmt.Errorf("You cannot exceed %d vehicles", p.VehicleLimit)
+	}
+
+	return nil
+}
+
+// CheckOrganisationCount returns an error if the plan doesn't allow creating another organisation
+// on top of the count already owned. Each organisation gets the plan's own vehicle limit, so
+// unlike vehicles there is no unlimited value.
+func (p *Plan) CheckOrganisationCount(count int64) error {
+	if count >= p.OrganisationLimit {
+		return fmt.Errorf("You cannot own more than %d organisations", p.OrganisationLimit)
+	}
+
+	return nil
+}
+
+// AllowsChannel is true if reminders may be sent over channel
+func (p *Plan) AllowsChannel(channel string) bool {
+	for _, allowed := range p.ReminderChannels {
+		if allowed == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckChannels returns an error for the first channel the plan doesn't allow
+func (p *Plan) CheckChannels(channels []string) error {
+	for _, channel := range channels {
+		if !p.AllowsChannel(channel) {
+			return fmt.Errorf("Reminders by %s are not included in your plan", channel)
+		}
+	}
+
+	return nil
+}
+
+// CheckAPITokens returns an error if the plan doesn't include API access
+func (p *Plan) CheckAPITokens() error {
+	if !p.APITokens {
+		return ErrAPITokensNotIncluded
+	}
+
+	return nil
+}
+
+// RefreshInterval is how long a vehicle's details are kept before being fetched again
+func (p *Plan) RefreshInterval() time.Duration {
+	return time.Duration(p.RefreshIntervalHours) * time.Hour
+}
+
+// RefreshDue is true if details last fetched at lastFetchedAt are due to be fetched again at now
+func (p *Plan) RefreshDue(lastFetchedAt, now time.Time) bool {
+	return !lastFetchedAt.After(now.Add(-p.RefreshInterval()))
+}
@@ -0,0 +1,143 @@
+package plans
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: Free, want: Free},
+		{name: Personal, want: Personal},
+		{name: Fleet, want: Fleet},
+		{name: "", want: Free},
+		{name: "enterprise", want: Free},
+	}
+
+	for _, tc := range testCases {
+		got := Get(tc.name)
+		if got.Name != tc.want {
+			t.Errorf("Expected plan '%s' for '%s' but got '%s'", tc.want, tc.name, got.Name)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	_, err := Lookup("enterprise")
+	if err != ErrUnknownPlan {
+		t.Errorf("Expected error '%s' but got '%v'", ErrUnknownPlan, err)
+	}
+
+	plan, err := Lookup(Personal)
+	if err != nil || plan.Name != Personal {
+		t.Errorf("Expected the personal plan but got %+v and '%v'", plan, err)
+	}
+}
+
+func TestCheckVehicleCount(t *testing.T) {
+	testCases := []struct {
+		limit   int64
+		count   int64
+		allowed bool
+	}{
+		{limit: 5, count: 0, allowed: true},
+		{limit: 5, count: 4, allowed: true},
+		{limit: 5, count: 5, allowed: false},
+		{limit: 5, count: 6, allowed: false},
+		{limit: 0, count: 1000, allowed: true},
+	}
+
+	for _, tc := range testCases {
+		plan := Plan{VehicleLimit: tc.limit}
+		err := plan.CheckVehicleCount(tc.count)
+		if (err == nil) != tc.allowed {
+			t.Errorf("Expected %d vehicles with limit %d to be allowed %t but got %v", tc.count, tc.limit, tc.allowed, err)
+		}
+	}
+}
+
+func TestCheckOrganisationCount(t *testing.T) {
+	testCases := []struct {
+		plan    string
+		count   int64
+		allowed bool
+	}{
+		{plan: Free, count: 0, allowed: true},
+		{plan: Free, count: 1, allowed: false},
+		{plan: Personal, count: 2, allowed: true},
+		{plan: Personal, count: 3, allowed: false},
+		{plan: Fleet, count: 9, allowed: true},
+		{plan: Fleet, count: 10, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		err := Get(tc.plan).CheckOrganisationCount(tc.count)
+		if (err == nil) != tc.allowed {
+			t.Errorf("Expected %s with %d organisations to be allowed %t but got %v", tc.plan, tc.count, tc.allowed, err)
+		}
+	}
+}
+
+func TestCheckChannels(t *testing.T) {
+	testCases := []struct {
+		plan     string
+		channels []string
+		allowed  bool
+	}{
+		{plan: Free, channels: nil, allowed: true},
+		{plan: Free, channels: []string{ChannelEmail}, allowed: true},
+		{plan: Free, channels: []string{ChannelEmail, ChannelWebhook}, allowed: false},
+		{plan: Personal, channels: []string{ChannelEmail, ChannelWebhook}, allowed: true},
+		{plan: Fleet, channels: []string{ChannelWebhook}, allowed: true},
+	}
+
+	for _, tc := range testCases {
+		err := Get(tc.plan).CheckChannels(tc.channels)
+		if (err == nil) != tc.allowed {
+			t.Errorf("Expected %s channels %v to be allowed %t but got %v", tc.plan, tc.channels, tc.allowed, err)
+		}
+	}
+}
+
+func TestCheckAPITokens(t *testing.T) {
+	if err := Get(Free).CheckAPITokens(); err != ErrAPITokensNotIncluded {
+		t.Errorf("Expected error '%s' but got '%v'", ErrAPITokensNotIncluded, err)
+	}
+
+	if err := Get(Personal).CheckAPITokens(); err != nil {
+		t.Errorf("Expected no error but got '%s'", err)
+	}
+}
+
+func TestRefreshDue(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		plan          string
+		lastFetchedAt time.Time
+		due           bool
+	}{
+		{plan: Free, lastFetchedAt: now.Add(-23 * time.Hour), due: false},
+		{plan: Free, lastFetchedAt: now.Add(-24 * time.Hour), due: true},
+		{plan: Personal, lastFetchedAt: now.Add(-5 * time.Hour), due: false},
+		{plan: Personal, lastFetchedAt: now.Add(-7 * time.Hour), due: true},
+		{plan: Fleet, lastFetchedAt: now.Add(-2 * time.Hour), due: true},
+		{plan: Fleet, lastFetchedAt: time.Time{}, due: true},
+	}
+
+	for _, tc := range testCases {
+		due := Get(tc.plan).RefreshDue(tc.lastFetchedAt, now)
+		if due != tc.due {
+			t.Errorf("Expected %s refresh due %t after fetching at %s but got %t", tc.plan, tc.due, tc.lastFetchedAt, due)
+		}
+	}
+}
+
+func TestMinRefreshInterval(t *testing.T) {
+	if got := MinRefreshInterval(); got != time.Hour {
+		t.Errorf("Expected minimum refresh interval 1h but got %s", got)
+	}
+}
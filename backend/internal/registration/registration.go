@@ -0,0 +1,192 @@
+package registration
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the style of a UK registration mark
+type Format string
+
+// Registration formats issued in the UK
+const (
+	FormatCurrent         Format = "current"
+	FormatPrefix          Format = "prefix"
+	FormatSuffix          Format = "suffix"
+	FormatDateless        Format = "dateless"
+	FormatNorthernIreland Format = "northern_ireland"
+	FormatQPlate          Format = "q_plate"
+)
+
+// ErrInvalid is returned when a registration does not match any format issued in the UK
+var ErrInvalid = errors.New("Registration Number must be valid")
+
+var (
+	currentPattern      = regexp.MustCompile(`^([A-Z]{2})([0-9]{2})([A-Z]{3})$`)
+	qPlatePattern       = regexp.MustCompile(`^Q([1-9][0-9]{0,2})([A-Z]{3})$`)
+	prefixPattern       = regexp.MustCompile(`^([A-Z])([1-9][0-9]{0,2})([A-Z]{3})$`)
+	suffixPattern       = regexp.MustCompile(`^([A-Z]{3})([1-9][0-9]{0,2})([A-Z])$`)
+	lettersFirstPattern = regexp.MustCompile(`^([A-Z]{1,3})([1-9][0-9]{0,3})$`)
+	numbersFirstPattern = regexp.MustCompile(`^([1-9][0-9]{0,3})([A-Z]{1,3})$`)
+)
+
+// prefixYears maps prefix letters to the date they were first issued
+var prefixYears = map[string]time.Time{
+	"A": date(1983, time.August), "B": date(1984, time.August), "C": date(1985, time.August),
+	"D": date(1986, time.August), "E": date(1987, time.August), "F": date(1988, time.August),
+	"G": date(1989, time.August), "H": date(1990, time.August), "J": date(1991, time.August),
+	"K": date(1992, time.August), "L": date(1993, time.August), "M": date(1994, time.August),
+	"N": date(1995, time.August), "P": date(1996, time.August), "R": date(1997, time.August),
+	"S": date(1998, time.August), "T": date(1999, time.March), "V": date(1999, time.September),
+	"W": date(2000, time.March), "X": date(2000, time.September), "Y": date(2001, time.March),
+}
+
+// suffixYears maps suffix letters to the date they were first issued
+var suffixYears = map[string]time.Time{
+	"A": date(1963, time.February), "B": date(1964, time.January), "C": date(1965, time.January),
+	"D": date(1966, time.January), "E": date(1967, time.January), "F": date(1967, time.August),
+	"G": date(1968, time.August), "H": date(1969, time.August), "J": date(1970, time.August),
+	"K": date(1971, time.August), "L": date(1972, time.August), "M": date(1973, time.August),
+	"N": date(1974, time.August), "P": date(1975, time.August), "R": date(1976, time.August),
+	"S": date(1977, time.August), "T": date(1978, time.August), "V": date(1979, time.August),
+	"W": date(1980, time.August), "X": date(1981, time.August), "Y": date(1982, time.August),
+}
+
+// Registration is a parsed and classified UK registration mark
+type Registration struct {
+	Number        string
+	Format        Format
+	AgeIdentifier string
+	IssuedFrom    time.Time
+}
+
+// now is the time registrations are checked against when deciding whether their age identifier has
+// been issued yet. Tests replace it to get the same answer every year.
+var now = time.Now
+
+// Normalise upper-cases a registration and strips any spaces or hyphens
+func Normalise(input string) string {
+	replacer := strings.NewReplacer(" ", "", "-", "", "\t", "")
+	return replacer.Replace(strings.ToUpper(strings.TrimSpace(input)))
+}
+
+// Parse normalises the input and classifies it. ErrInvalid is returned if the registration could
+// not have been issued.
+func Parse(input string) (*Registration, error) {
+	number := Normalise(input)
+
+	if len(number) < 2 || len(number) > 7 {
+		return nil, ErrInvalid
+	}
+
+	if m := currentPattern.FindStringSubmatch(number); m != nil {
+		return parseCurrent(number, m[1], m[2], m[3])
+	}
+
+	if m := qPlatePattern.FindStringSubmatch(number); m != nil {
+		if !validLetters(m[2], "IQ") {
+			return nil, ErrInvalid
+		}
+		return &Registration{Number: number, Format: FormatQPlate}, nil
+	}
+
+	if m := prefixPattern.FindStringSubmatch(number); m != nil {
+		issued, ok := prefixYears[m[1]]
+		if !ok || !validLetters(m[3], "IQ") {
+			return nil, ErrInvalid
+		}
+		return &Registration{Number: number, Format: FormatPrefix, AgeIdentifier: m[1], IssuedFrom: issued}, nil
+	}
+
+	if m := suffixPattern.FindStringSubmatch(number); m != nil {
+		issued, ok := suffixYears[m[3]]
+		if ok && validLetters(m[1], "Q") {
+			return &Registration{Number: number, Format: FormatSuffix, AgeIdentifier: m[3], IssuedFrom: issued}, nil
+		}
+	}
+
+	if m := lettersFirstPattern.FindStringSubmatch(number); m != nil {
+		return parseDateless(number, m[1])
+	}
+
+	if m := numbersFirstPattern.FindStringSubmatch(number); m != nil {
+		return parseDateless(number, m[2])
+	}
+
+	return nil, ErrInvalid
+}
+
+// Formatted returns the registration with the conventional spacing, e.g. AB12 CDE
+func (r *Registration) Formatted() string {
+	switch r.Format {
+	case FormatCurrent:
+		return r.Number[:4] + " " + r.Number[4:]
+	case FormatPrefix, FormatQPlate:
+		return r.Number[:len(r.Number)-3] + " " + r.Number[len(r.Number)-3:]
+	case FormatSuffix:
+		return r.Number[:3] + " " + r.Number[3:]
+	}
+
+	// Dateless and Northern Ireland marks split between the letters and numbers
+	for i := 1; i < len(r.Number); i++ {
+		if isDigit(r.Number[i]) != isDigit(r.Number[i-1]) {
+			return r.Number[:i] + " " + r.Number[i:]
+		}
+	}
+
+	return r.Number
+}
+
+func parseCurrent(number, area, age, letters string) (*Registration, error) {
+	if !validLetters(area[:1], "IQZ") || !validLetters(area[1:], "IQZ") || !validLetters(letters, "IQ") {
+		return nil, ErrInvalid
+	}
+
+	ageNumber, _ := strconv.Atoi(age)
+
+	var issued time.Time
+	switch {
+	case ageNumber >= 2 && ageNumber <= 50:
+		issued = date(2000+ageNumber, time.March)
+	case ageNumber >= 51:
+		issued = date(2000+ageNumber-50, time.September)
+	default:
+		return nil, ErrInvalid
+	}
+
+	if issued.After(now()) {
+		return nil, fmt.Errorf("Age identifier %s has not been issued yet", age)
+	}
+
+	return &Registration{Number: number, Format: FormatCurrent, AgeIdentifier: age, IssuedFrom: issued}, nil
+}
+
+// parseDateless distinguishes Northern Ireland marks, which always contain an I or Z in their
+// letters, from dateless marks issued in Great Britain before 1963
+func parseDateless(number, letters string) (*Registration, error) {
+	if strings.Contains(letters, "Q") {
+		return nil, ErrInvalid
+	}
+
+	if strings.ContainsAny(letters, "IZ") {
+		return &Registration{Number: number, Format: FormatNorthernIreland}, nil
+	}
+
+	return &Registration{Number: number, Format: FormatDateless}, nil
+}
+
+func validLetters(letters, excluded string) bool {
+	return !strings.ContainsAny(letters, excluded)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func date(year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,69 @@
+package registration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now = func() time.Time { return date(2021, time.June) }
+	defer func() { now = time.Now }()
+
+	testCases := []struct {
+		input     string
+		number    string
+		format    Format
+		age       string
+		issued    time.Time
+		formatted string
+		invalid   bool
+	}{
+		{input: "ab12 cde", number: "AB12CDE", format: FormatCurrent, age: "12", issued: date(2012, time.March), formatted: "AB12 CDE"},
+		{input: "AB62CDE", number: "AB62CDE", format: FormatCurrent, age: "62", issued: date(2012, time.September), formatted: "AB62 CDE"},
+		{input: "P239 FWP", number: "P239FWP", format: FormatPrefix, age: "P", issued: date(1996, time.August), formatted: "P239 FWP"},
+		{input: "ABC 123A", number: "ABC123A", format: FormatSuffix, age: "A", issued: date(1963, time.February), formatted: "ABC 123A"},
+		{input: "ABC 1", number: "ABC1", format: FormatDateless, formatted: "ABC 1"},
+		{input: "1 ABC", number: "1ABC", format: FormatDateless, formatted: "1 ABC"},
+		{input: "AIZ 1234", number: "AIZ1234", format: FormatNorthernIreland, formatted: "AIZ 1234"},
+		{input: "Q123 ABC", number: "Q123ABC", format: FormatQPlate, formatted: "Q123 ABC"},
+		{input: "AB-12-CDE", number: "AB12CDE", format: FormatCurrent, age: "12", issued: date(2012, time.March), formatted: "AB12 CDE"},
+		{input: "AB21CDE", number: "AB21CDE", format: FormatCurrent, age: "21", issued: date(2021, time.March), formatted: "AB21 CDE"},
+		{input: "AB01CDE", invalid: true},
+		{input: "AB00CDE", invalid: true},
+		{input: "AB71CDE", invalid: true},
+		{input: "AB22CDE", invalid: true},
+		{input: "IB12CDE", invalid: true},
+		{input: "AB12CDQ", invalid: true},
+		{input: "I123ABC", invalid: true},
+		{input: "A012BCD", invalid: true},
+		{input: "AB_12[", invalid: true},
+		{input: "ABCD123", invalid: true},
+		{input: "A", invalid: true},
+		{input: "", invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			reg, err := Parse(tc.input)
+
+			if tc.invalid {
+				if err == nil {
+					t.Errorf("Expected an error but got %+v", reg)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got '%s'", err)
+			}
+
+			if reg.Number != tc.number || reg.Format != tc.format || reg.AgeIdentifier != tc.age || !reg.IssuedFrom.Equal(tc.issued) {
+				t.Errorf("Expected %s %s %s %s but got %+v", tc.number, tc.format, tc.age, tc.issued, reg)
+			}
+
+			if reg.Formatted() != tc.formatted {
+				t.Errorf("Expected formatted '%s' but got '%s'", tc.formatted, reg.Formatted())
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 secret used by the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 Appendix B vectors, truncated to six digits
+	testCases := []struct {
+		time int64
+		code string
+	}{
+		{time: 59, code: "287082"},
+		{time: 1111111109, code: "081804"},
+		{time: 1111111111, code: "050471"},
+		{time: 1234567890, code: "005924"},
+		{time: 2000000000, code: "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(rfcSecret, Step(time.Unix(tc.time, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if code != tc.code {
+			t.Errorf("Expected code %s at %d but got %s", tc.code, tc.time, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	testCases := []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{name: "current code", code: "050471", valid: true},
+		{name: "previous code within skew", code: "081804", valid: true},
+		{name: "spaces are ignored", code: "050 471", valid: true},
+		{name: "wrong code", code: "123456", valid: false},
+		{name: "wrong length", code: "50471", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := Validate(rfcSecret, tc.code, now)
+			if ok != tc.valid {
+				t.Errorf("Expected valid %t but got %t", tc.valid, ok)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("MOT.ninja", "user@example.com", "ABC")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/MOT.ninja:user@example.com?") {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+
+	if !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=MOT.ninja") {
+		t.Errorf("Expected secret and issuer in URI %s", uri)
+	}
+}
@@ -0,0 +1,163 @@
+package usecases
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+const milesPerKilometre = 0.621371
+
+// advisoryTypes are the MOT comment types which don't fail a test but are worth watching
+var advisoryTypes = map[string]bool{"ADVISORY": true, "MINOR": true}
+
+// VehicleReport is a vehicle's details along with what can be derived from its MOT history
+type VehicleReport struct {
+	Vehicle    *models.Vehicle
+	Mileage    MileageAnalysis
+	Advisories AdvisoryAnalysis
+}
+
+// MileageReading is an odometer reading taken at an MOT test, in miles
+type MileageReading struct {
+	Date  time.Time
+	Miles int
+}
+
+// MileageAnalysis summarises the odometer readings taken at MOT tests. A discrepancy is a reading
+// lower than the one before it, which may mean the odometer was replaced or tampered with. The
+// average is only given for at least a year of consistent readings.
+type MileageAnalysis struct {
+	Readings            []MileageReading
+	LatestMiles         int
+	AverageMilesPerYear int
+	Discrepancies       []MileageDiscrepancy
+}
+
+// MileageDiscrepancy is a reading which went backwards from the one before it
+type MileageDiscrepancy struct {
+	Date          time.Time
+	PreviousMiles int
+	Miles         int
+}
+
+// AdvisoryAnalysis lists the advisories from the most recent MOT test, and which of them were also
+// given at an earlier test
+type AdvisoryAnalysis struct {
+	Latest    []string
+	Recurring []string
+}
+
+// BuildVehicleReport derives a report from a vehicle's MOT history
+func BuildVehicleReport(vehicle *models.Vehicle) *VehicleReport {
+	history := make([]models.MOTTest, len(vehicle.MOTHistory))
+	copy(history, vehicle.MOTHistory)
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].CompletedDate.Before(history[j].CompletedDate)
+	})
+
+	return &VehicleReport{
+		Vehicle:    vehicle,
+		Mileage:    analyseMileage(history),
+		Advisories: analyseAdvisories(history),
+	}
+}
+
+// analyseMileage expects history oldest first
+func analyseMileage(history []models.MOTTest) MileageAnalysis {
+	analysis := MileageAnalysis{Readings: []MileageReading{}, Discrepancies: []MileageDiscrepancy{}}
+
+	for _, test := range history {
+		miles, ok := parseOdometerReading(test.OdometerReading)
+		if !ok {
+			continue
+		}
+
+		if len(analysis.Readings) > 0 {
+			previous := analysis.Readings[len(analysis.Readings)-1]
+			if miles < previous.Miles {
+				analysis.Discrepancies = append(analysis.Discrepancies, MileageDiscrepancy{
+					Date:          test.CompletedDate,
+					PreviousMiles: previous.Miles,
+					Miles:         miles,
+				})
+			}
+		}
+
+		analysis.Readings = append(analysis.Readings, MileageReading{Date: test.CompletedDate, Miles: miles})
+	}
+
+	if len(analysis.Readings) == 0 {
+		return analysis
+	}
+
+	first := analysis.Readings[0]
+	latest := analysis.Readings[len(analysis.Readings)-1]
+	analysis.LatestMiles = latest.Miles
+
+	// An average across a discrepancy would be meaningless
+	years := latest.Date.Sub(first.Date).Hours() / (24 * 365.25)
+	if years >= 1 && len(analysis.Discrepancies) == 0 {
+		analysis.AverageMilesPerYear = int(float64(latest.Miles-first.Miles) / years)
+	}
+
+	return analysis
+}
+
+// parseOdometerReading reads a value such as "12345 mi" or "20000 km", converting to miles.
+// Readings which weren't taken are recorded as zero and are skipped.
+func parseOdometerReading(reading string) (int, bool) {
+	fields := strings.Fields(reading)
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	value, err := strconv.Atoi(fields[0])
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+
+	if len(fields) > 1 && strings.EqualFold(fields[1], "km") {
+		value = int(float64(value) * milesPerKilometre)
+	}
+
+	return value, true
+}
+
+// analyseAdvisories expects history oldest first
+func analyseAdvisories(history []models.MOTTest) AdvisoryAnalysis {
+	analysis := AdvisoryAnalysis{Latest: []string{}, Recurring: []string{}}
+
+	if len(history) == 0 {
+		return analysis
+	}
+
+	earlier := make(map[string]bool)
+	for _, test := range history[:len(history)-1] {
+		for _, comment := range test.RfrAndComments {
+			if advisoryTypes[comment.Type] {
+				earlier[normaliseComment(comment.Comment)] = true
+			}
+		}
+	}
+
+	for _, comment := range history[len(history)-1].RfrAndComments {
+		if !advisoryTypes[comment.Type] {
+			continue
+		}
+
+		analysis.Latest = append(analysis.Latest, comment.Comment)
+		if earlier[normaliseComment(comment.Comment)] {
+			analysis.Recurring = append(analysis.Recurring, comment.Comment)
+		}
+	}
+
+	return analysis
+}
+
+func normaliseComment(comment string) string {
+	return strings.ToLower(strings.Join(strings.Fields(comment), " "))
+}
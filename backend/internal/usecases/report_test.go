@@ -0,0 +1,102 @@
+package usecases
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+func TestBuildVehicleReportMileage(t *testing.T) {
+	date := func(year int) time.Time {
+		return time.Date(year, 3, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name          string
+		history       []models.MOTTest
+		latest        int
+		perYear       int
+		discrepancies int
+	}{
+		{
+			name: "no history",
+		},
+		{
+			name: "steady mileage out of order",
+			history: []models.MOTTest{
+				{CompletedDate: date(2021), OdometerReading: "30000 mi"},
+				{CompletedDate: date(2019), OdometerReading: "10000 mi"},
+				{CompletedDate: date(2020), OdometerReading: "20000 mi"},
+			},
+			latest:  30000,
+			perYear: 9993,
+		},
+		{
+			name: "kilometres and unread odometer",
+			history: []models.MOTTest{
+				{CompletedDate: date(2019), OdometerReading: "10000 km"},
+				{CompletedDate: date(2020), OdometerReading: "0 "},
+			},
+			latest: 6213,
+		},
+		{
+			name: "clocked",
+			history: []models.MOTTest{
+				{CompletedDate: date(2019), OdometerReading: "80000 mi"},
+				{CompletedDate: date(2020), OdometerReading: "40000 mi"},
+			},
+			latest:        40000,
+			discrepancies: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := BuildVehicleReport(&models.Vehicle{MOTHistory: tc.history})
+
+			if report.Mileage.LatestMiles != tc.latest {
+				t.Errorf("expected latest mileage %d, got %d", tc.latest, report.Mileage.LatestMiles)
+			}
+			if report.Mileage.AverageMilesPerYear != tc.perYear {
+				t.Errorf("expected %d miles per year, got %d", tc.perYear, report.Mileage.AverageMilesPerYear)
+			}
+			if len(report.Mileage.Discrepancies) != tc.discrepancies {
+				t.Errorf("expected %d discrepancies, got %d", tc.discrepancies, len(report.Mileage.Discrepancies))
+			}
+		})
+	}
+}
+
+func TestBuildVehicleReportAdvisories(t *testing.T) {
+	history := []models.MOTTest{
+		{
+			CompletedDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			RfrAndComments: []models.RfrAndComments{
+				{Type: "ADVISORY", Comment: "Nearside front tyre worn close to legal limit"},
+				{Type: "MINOR", Comment: "Oil leak"},
+				{Type: "FAIL", Comment: "Headlamp aim too high"},
+			},
+		},
+		{
+			CompletedDate: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+			RfrAndComments: []models.RfrAndComments{
+				{Type: "ADVISORY", Comment: "nearside front tyre  worn close to legal limit"},
+				{Type: "FAIL", Comment: "Oil leak"},
+			},
+		},
+	}
+
+	report := BuildVehicleReport(&models.Vehicle{MOTHistory: history})
+
+	latest := []string{"Nearside front tyre worn close to legal limit", "Oil leak"}
+	if !reflect.DeepEqual(report.Advisories.Latest, latest) {
+		t.Errorf("expected latest advisories %v, got %v", latest, report.Advisories.Latest)
+	}
+
+	recurring := []string{"Nearside front tyre worn close to legal limit"}
+	if !reflect.DeepEqual(report.Advisories.Recurring, recurring) {
+		t.Errorf("expected recurring advisories %v, got %v", recurring, report.Advisories.Recurring)
+	}
+}
@@ -0,0 +1,91 @@
+package usecases
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+// DetectStatusChanges compares a stored vehicle with freshly fetched details and returns an event
+// for each change in V5C issue date, export marker or tax status. Fields which were never
+// populated on the stored vehicle are ignored so the first refresh does not raise spurious events.
+func DetectStatusChanges(previous, current *models.Vehicle) []models.VehicleEvent {
+	var events []models.VehicleEvent
+
+	prevV5C := previous.DateOfLastV5CIssued
+	currV5C := current.DateOfLastV5CIssued
+	if !prevV5C.IsZero() && !currV5C.IsZero() && !prevV5C.Equal(currV5C) {
+		events = append(events, models.VehicleEvent{
+			VehicleID:   previous.ID,
+			Type:        models.VehicleEventV5CIssued,
+			Description: fmt.Sprintf("A new V5C was issued for %s on %s, which may indicate a change of keeper", previous.RegistrationNumber, currV5C.Format("2 January 2006")),
+			OldValue:    prevV5C.Format("2006-01-02"),
+			NewValue:    currV5C.Format("2006-01-02"),
+		})
+	}
+
+	if !previous.MarkedForExport && current.MarkedForExport && previous.TaxStatus != "" {
+		events = append(events, models.VehicleEvent{
+			VehicleID:   previous.ID,
+			Type:        models.VehicleEventMarkedForExport,
+			Description: fmt.Sprintf("%s has been marked for export", previous.RegistrationNumber),
+			OldValue:    "false",
+			NewValue:    "true",
+		})
+	}
+
+	if previous.TaxStatus != "" && current.TaxStatus != "" && !strings.EqualFold(previous.TaxStatus, current.TaxStatus) {
+		events = append(events, models.VehicleEvent{
+			VehicleID:   previous.ID,
+			Type:        models.VehicleEventTaxStatus,
+			Description: fmt.Sprintf("Tax status of %s changed from %s to %s", previous.RegistrationNumber, previous.TaxStatus, current.TaxStatus),
+			OldValue:    previous.TaxStatus,
+			NewValue:    current.TaxStatus,
+		})
+	}
+
+	return events
+}
+
+// DetectPlateTransfer returns an event if the registration now resolves to a different DVSA vehicle
+// than the one stored, which happens when a cherished plate is moved between vehicles. Nil is
+// returned if the vehicle is unchanged or either ID is unknown.
+func DetectPlateTransfer(previous, current *models.Vehicle) *models.VehicleEvent {
+	if previous.DVSAVehicleID == "" || current.DVSAVehicleID == "" || previous.DVSAVehicleID == current.DVSAVehicleID {
+		return nil
+	}
+
+	return &models.VehicleEvent{
+		VehicleID:   previous.ID,
+		Type:        models.VehicleEventPlateTransfer,
+		Description: fmt.Sprintf("The registration %s has been moved to a different vehicle (%s %s). Updates have been paused, follow your vehicle to its new registration to keep its history.", previous.RegistrationNumber, current.Manufacturer, current.Model),
+		OldValue:    previous.DVSAVehicleID,
+		NewValue:    current.DVSAVehicleID,
+	}
+}
+
+// MergeMOTHistory combines the history fetched under a vehicle's new registration with the history
+// previously stored, so no tests are lost when following a plate transfer. Tests are returned
+// newest first.
+func MergeMOTHistory(current, previous []models.MOTTest) []models.MOTTest {
+	seen := make(map[int]bool)
+	var merged []models.MOTTest
+
+	for _, tests := range [][]models.MOTTest{current, previous} {
+		for _, test := range tests {
+			if seen[test.TestNumber] {
+				continue
+			}
+			seen[test.TestNumber] = true
+			merged = append(merged, test)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CompletedDate.After(merged[j].CompletedDate)
+	})
+
+	return merged
+}
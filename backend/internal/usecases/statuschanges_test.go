@@ -0,0 +1,177 @@
+package usecases
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+)
+
+func TestDetectStatusChanges(t *testing.T) {
+	v5c := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newV5C := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		previous models.VehicleSpec
+		current  models.VehicleSpec
+		types    []string
+	}{
+		{
+			name:     "no changes",
+			previous: models.VehicleSpec{TaxStatus: "Taxed", DateOfLastV5CIssued: v5c},
+			current:  models.VehicleSpec{TaxStatus: "Taxed", DateOfLastV5CIssued: v5c},
+		},
+		{
+			name:     "never populated",
+			previous: models.VehicleSpec{},
+			current:  models.VehicleSpec{TaxStatus: "SORN", MarkedForExport: true, DateOfLastV5CIssued: v5c},
+		},
+		{
+			name:     "new v5c",
+			previous: models.VehicleSpec{TaxStatus: "Taxed", DateOfLastV5CIssued: v5c},
+			current:  models.VehicleSpec{TaxStatus: "Taxed", DateOfLastV5CIssued: newV5C},
+			types:    []string{models.VehicleEventV5CIssued},
+		},
+		{
+			name:     "declared sorn and exported",
+			previous: models.VehicleSpec{TaxStatus: "Taxed"},
+			current:  models.VehicleSpec{TaxStatus: "SORN", MarkedForExport: true},
+			types:    []string{models.VehicleEventMarkedForExport, models.VehicleEventTaxStatus},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := &models.Vehicle{RegistrationNumber: "AB12CDE", VehicleSpec: tc.previous}
+			current := &models.Vehicle{RegistrationNumber: "AB12CDE", VehicleSpec: tc.current}
+
+			var types []string
+			for _, event := range DetectStatusChanges(previous, current) {
+				types = append(types, event.Type)
+			}
+
+			if !reflect.DeepEqual(types, tc.types) {
+				t.Errorf("Expected events %v but got %v", tc.types, types)
+			}
+		})
+	}
+}
+
+func TestDetectPlateTransfer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		previous string
+		current  string
+		transfer bool
+	}{
+		{name: "same vehicle", previous: "abc", current: "abc"},
+		{name: "unknown stored id", previous: "", current: "abc"},
+		{name: "unknown fetched id", previous: "abc", current: ""},
+		{name: "different vehicle", previous: "abc", current: "xyz", transfer: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := &models.Vehicle{VehicleSpec: models.VehicleSpec{DVSAVehicleID: tc.previous}}
+			current := &models.Vehicle{VehicleSpec: models.VehicleSpec{DVSAVehicleID: tc.current}}
+
+			event := DetectPlateTransfer(previous, current)
+			if (event != nil) != tc.transfer {
+				t.Errorf("Expected transfer %t but got %v", tc.transfer, event)
+			}
+		})
+	}
+}
+
+func TestMergeMOTHistory(t *testing.T) {
+	june2018 := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	june2019 := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	june2020 := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	june2021 := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		current  []models.MOTTest
+		previous []models.MOTTest
+		merged   []models.MOTTest
+	}{
+		{
+			name: "no previous history",
+			current: []models.MOTTest{
+				{TestNumber: 3, Passed: true, CompletedDate: june2020},
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+			},
+			merged: []models.MOTTest{
+				{TestNumber: 3, Passed: true, CompletedDate: june2020},
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+			},
+		},
+		{
+			name: "no current history",
+			previous: []models.MOTTest{
+				{TestNumber: 1, Passed: true, CompletedDate: june2018},
+			},
+			merged: []models.MOTTest{
+				{TestNumber: 1, Passed: true, CompletedDate: june2018},
+			},
+		},
+		{
+			name: "separate histories are interleaved newest first",
+			current: []models.MOTTest{
+				{TestNumber: 4, Passed: true, CompletedDate: june2021},
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+			},
+			previous: []models.MOTTest{
+				{TestNumber: 3, Passed: false, CompletedDate: june2020},
+				{TestNumber: 1, Passed: true, CompletedDate: june2018},
+			},
+			merged: []models.MOTTest{
+				{TestNumber: 4, Passed: true, CompletedDate: june2021},
+				{TestNumber: 3, Passed: false, CompletedDate: june2020},
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+				{TestNumber: 1, Passed: true, CompletedDate: june2018},
+			},
+		},
+		{
+			name: "overlapping histories keep one copy of each test",
+			current: []models.MOTTest{
+				{TestNumber: 3, Passed: true, CompletedDate: june2020},
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+			},
+			previous: []models.MOTTest{
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+				{TestNumber: 1, Passed: true, CompletedDate: june2018},
+			},
+			merged: []models.MOTTest{
+				{TestNumber: 3, Passed: true, CompletedDate: june2020},
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+				{TestNumber: 1, Passed: true, CompletedDate: june2018},
+			},
+		},
+		{
+			name: "duplicate test numbers prefer the current history",
+			current: []models.MOTTest{
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+			},
+			previous: []models.MOTTest{
+				{TestNumber: 2, Passed: false, CompletedDate: june2019},
+				{TestNumber: 2, Passed: false, CompletedDate: june2019},
+			},
+			merged: []models.MOTTest{
+				{TestNumber: 2, Passed: true, CompletedDate: june2019},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := MergeMOTHistory(tc.current, tc.previous)
+
+			if !reflect.DeepEqual(merged, tc.merged) {
+				t.Errorf("Expected history %+v but got %+v", tc.merged, merged)
+			}
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/darkphnx/vehiclemanager/internal/vesapi"
 )
 
+// firstMotAgeYears is the age at which a vehicle requires its first MOT
+const firstMotAgeYears = 3
+
 // VehicleDetails is a wrapper for the APIs necessary to fetch vehicle details
 type VehicleDetails struct {
 	VehicleEnquiryServiceAPI *vesapi.Client
@@ -23,7 +26,13 @@ func (a *VehicleDetails) Fetch(registrationNumber string) (*models.Vehicle, erro
 	}
 
 	vehicleHistory, err := a.MotHistoryAPI.GetVehicleHistory(registrationNumber)
-	if err != nil {
+	if err == mothistoryapi.ErrNoHistory {
+		// Vehicles under three years old have no history yet, so fall back to what VES knows
+		vehicleHistory = &mothistoryapi.Vehicle{
+			Registration: vehicleStatus.RegistrationNumber,
+			Make:         vehicleStatus.Make,
+		}
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -50,15 +59,85 @@ func (a *VehicleDetails) Fetch(registrationNumber string) (*models.Vehicle, erro
 		motHistory = append(motHistory, test)
 	}
 
+	motDue, noMotYet := motDueDate(vehicleStatus, vehicleHistory)
+
 	vehicle := models.Vehicle{
 		RegistrationNumber: vehicleStatus.RegistrationNumber,
 		Manufacturer:       vehicleHistory.Make,
 		Model:              vehicleHistory.Model,
-		MotDue:             vehicleHistory.MotTests[0].ExpiryDate.Time,
+		MotDue:             motDue,
+		NoMotYet:           noMotYet,
 		VEDDue:             vehicleStatus.TaxDueDate.Time,
 		MOTHistory:         motHistory,
 		LastFetchedAt:      time.Now(),
+		VehicleSpec:        vehicleSpec(vehicleStatus, vehicleHistory),
 	}
 
 	return &vehicle, nil
 }
+
+func vehicleSpec(status *vesapi.VehicleStatus, history *mothistoryapi.Vehicle) models.VehicleSpec {
+	return models.VehicleSpec{
+		DVSAVehicleID:            history.VehicleID,
+		Colour:                   status.Colour,
+		PrimaryColour:            history.PrimaryColour,
+		FuelType:                 status.FuelType,
+		Co2Emissions:             status.Co2Emissions,
+		EngineCapacity:           status.EngineCapacity,
+		EngineSize:               history.EngineSize,
+		TaxStatus:                status.TaxStatus,
+		MotStatus:                status.MotStatus,
+		EuroStatus:               status.EuroStatus,
+		RealDrivingEmissions:     status.RealDrivingEmissions,
+		MarkedForExport:          status.MarkedForExport,
+		Wheelplan:                status.Wheelplan,
+		TypeApproval:             status.TypeApproval,
+		RevenueWeight:            status.RevenueWeight,
+		YearOfManufacture:        status.YearOfManufacture,
+		MonthOfFirstRegistration: status.MonthOfFirstRegistration,
+		FirstUsedDate:            history.FirstUsedDate.Time,
+		RegistrationDate:         history.RegistrationDate.Time,
+		ManufactureDate:          history.ManufactureDate.Time,
+		DateOfLastV5CIssued:      status.DateOfLastV5CIssued.Time,
+		ArtEndDate:               status.ArtEndDate,
+	}
+}
+
+// motDueDate returns the expiry of the most recent MOT which has one. Vehicles which have never
+// been tested are due their first MOT on the third anniversary of registration, in which case
+// noMotYet is true.
+func motDueDate(status *vesapi.VehicleStatus, history *mothistoryapi.Vehicle) (due time.Time, noMotYet bool) {
+	for _, test := range history.MotTests {
+		if !test.ExpiryDate.IsZero() {
+			return test.ExpiryDate.Time, false
+		}
+	}
+
+	if len(history.MotTests) > 0 {
+		// Only failed tests on record, so the vehicle is due now
+		return history.MotTests[0].CompletedDate.Time, false
+	}
+
+	registered := firstRegistrationDate(status, history)
+	if registered.IsZero() {
+		return time.Time{}, true
+	}
+
+	return registered.AddDate(firstMotAgeYears, 0, 0), true
+}
+
+// firstRegistrationDate prefers the exact registration date from the MOT history. VES only
+// provides the month of first registration, so the start of the month is used to err on the
+// side of an early reminder.
+func firstRegistrationDate(status *vesapi.VehicleStatus, history *mothistoryapi.Vehicle) time.Time {
+	if !history.RegistrationDate.IsZero() {
+		return history.RegistrationDate.Time
+	}
+
+	registered, err := time.Parse("2006-01", status.MonthOfFirstRegistration)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return registered
+}
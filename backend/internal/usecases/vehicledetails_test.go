@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/darkphnx/vehiclemanager/internal/models"
+	"github.com/darkphnx/vehiclemanager/internal/mothistoryapi"
+	"github.com/darkphnx/vehiclemanager/internal/vesapi"
+)
+
+func TestMotDueDate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   *vesapi.VehicleStatus
+		history  *mothistoryapi.Vehicle
+		due      time.Time
+		noMotYet bool
+	}{
+		{
+			name:   "uses expiry of most recent pass",
+			status: &vesapi.VehicleStatus{},
+			history: &mothistoryapi.Vehicle{
+				MotTests: []mothistoryapi.MotTest{
+					{TestResult: "FAILED"},
+					{TestResult: "PASSED", ExpiryDate: mothistoryapi.DottedDate{Time: time.Date(2021, 10, 20, 0, 0, 0, 0, time.UTC)}},
+				},
+			},
+			due: time.Date(2021, 10, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "uses exact registration date when no tests",
+			status: &vesapi.VehicleStatus{MonthOfFirstRegistration: "2020-03"},
+			history: &mothistoryapi.Vehicle{
+				RegistrationDate: mothistoryapi.DottedDate{Time: time.Date(2020, 3, 14, 0, 0, 0, 0, time.UTC)},
+			},
+			due:      time.Date(2023, 3, 14, 0, 0, 0, 0, time.UTC),
+			noMotYet: true,
+		},
+		{
+			name:     "falls back to month of first registration",
+			status:   &vesapi.VehicleStatus{MonthOfFirstRegistration: "2020-03"},
+			history:  &mothistoryapi.Vehicle{},
+			due:      time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+			noMotYet: true,
+		},
+		{
+			name:     "unknown registration date",
+			status:   &vesapi.VehicleStatus{},
+			history:  &mothistoryapi.Vehicle{},
+			noMotYet: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			due, noMotYet := motDueDate(tc.status, tc.history)
+
+			if !due.Equal(tc.due) {
+				t.Errorf("Expected due date %s but got %s", tc.due, due)
+			}
+
+			if noMotYet != tc.noMotYet {
+				t.Errorf("Expected noMotYet %t but got %t", tc.noMotYet, noMotYet)
+			}
+		})
+	}
+}
+
+func TestVehicleSpec(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  *vesapi.VehicleStatus
+		history *mothistoryapi.Vehicle
+		spec    models.VehicleSpec
+	}{
+		{
+			name: "maps both sources",
+			status: &vesapi.VehicleStatus{
+				ArtEndDate:               "2026-03-31",
+				Co2Emissions:             120,
+				Colour:                   "BLUE",
+				EngineCapacity:           1598,
+				FuelType:                 "PETROL",
+				MarkedForExport:          true,
+				MonthOfFirstRegistration: "2016-03",
+				MotStatus:                "Valid",
+				RevenueWeight:            1850,
+				TaxStatus:                "Taxed",
+				TypeApproval:             "M1",
+				Wheelplan:                "2 AXLE RIGID BODY",
+				YearOfManufacture:        2016,
+				EuroStatus:               "EURO 6",
+				RealDrivingEmissions:     "1",
+				DateOfLastV5CIssued:      vesapi.Date{Time: time.Date(2019, 5, 14, 0, 0, 0, 0, time.UTC)},
+			},
+			history: &mothistoryapi.Vehicle{
+				VehicleID:        "4Tq319nVKLz+25IRaUo79w==",
+				PrimaryColour:    "Blue",
+				EngineSize:       1598,
+				FirstUsedDate:    mothistoryapi.DottedDate{Time: time.Date(2016, 3, 10, 0, 0, 0, 0, time.UTC)},
+				RegistrationDate: mothistoryapi.DottedDate{Time: time.Date(2016, 3, 11, 0, 0, 0, 0, time.UTC)},
+				ManufactureDate:  mothistoryapi.DottedDate{Time: time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			spec: models.VehicleSpec{
+				DVSAVehicleID:            "4Tq319nVKLz+25IRaUo79w==",
+				Colour:                   "BLUE",
+				PrimaryColour:            "Blue",
+				FuelType:                 "PETROL",
+				Co2Emissions:             120,
+				EngineCapacity:           1598,
+				EngineSize:               1598,
+				TaxStatus:                "Taxed",
+				MotStatus:                "Valid",
+				EuroStatus:               "EURO 6",
+				RealDrivingEmissions:     "1",
+				MarkedForExport:          true,
+				Wheelplan:                "2 AXLE RIGID BODY",
+				TypeApproval:             "M1",
+				RevenueWeight:            1850,
+				YearOfManufacture:        2016,
+				MonthOfFirstRegistration: "2016-03",
+				FirstUsedDate:            time.Date(2016, 3, 10, 0, 0, 0, 0, time.UTC),
+				RegistrationDate:         time.Date(2016, 3, 11, 0, 0, 0, 0, time.UTC),
+				ManufactureDate:          time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC),
+				DateOfLastV5CIssued:      time.Date(2019, 5, 14, 0, 0, 0, 0, time.UTC),
+				ArtEndDate:               "2026-03-31",
+			},
+		},
+		{
+			name:    "missing details are left empty",
+			status:  &vesapi.VehicleStatus{TaxStatus: "SORN"},
+			history: &mothistoryapi.Vehicle{},
+			spec:    models.VehicleSpec{TaxStatus: "SORN"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := vehicleSpec(tc.status, tc.history)
+
+			if !reflect.DeepEqual(spec, tc.spec) {
+				t.Errorf("Expected spec %+v but got %+v", tc.spec, spec)
+			}
+		})
+	}
+}
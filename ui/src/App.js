@@ -7,6 +7,10 @@ import {
 } from "react-router-dom";
 import Signup from './pages/Signup';
 import Login from './pages/Login';
+import VerifyEmail from './pages/VerifyEmail';
+import ForgotPassword from './pages/ForgotPassword';
+import ResetPassword from './pages/ResetPassword';
+import AcceptInvitation from './pages/AcceptInvitation';
 import VehicleList from './pages/VehicleList';
 import VehicleHistory from './pages/VehicleHistory';
 
@@ -33,6 +37,18 @@ function App() {
             <Route path="/login">
               <Login />
             </Route>
+            <Route path="/verify-email">
+              <VerifyEmail />
+            </Route>
+            <Route path="/password/forgot">
+              <ForgotPassword />
+            </Route>
+            <Route path="/password/reset">
+              <ResetPassword />
+            </Route>
+            <Route path="/invitations/accept">
+              <AcceptInvitation />
+            </Route>
             <Route path="/:registrationNumber">
               <VehicleHistory />
             </Route>
@@ -0,0 +1,42 @@
+// Wraps fetch for calls to /api. Access tokens are short-lived, so when one has expired the
+// refresh token cookie is exchanged for a new one and the request is retried once.
+let refreshing = null;
+
+// The server sets a readable csrf_token cookie at login which must be echoed back in a header on
+// any request that changes something.
+function csrfToken() {
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+  return match ? decodeURIComponent(match[1]) : '';
+}
+
+function withCsrfToken(options = {}) {
+  return {
+    ...options,
+    headers: { ...options.headers, 'X-CSRF-Token': csrfToken() },
+  };
+}
+
+function refreshTokens() {
+  if(!refreshing) {
+    refreshing = fetch('/token/refresh', withCsrfToken({ method: 'POST' }))
+      .then(response => response.ok)
+      .finally(() => { refreshing = null; });
+  }
+
+  return refreshing;
+}
+
+export default async function apiFetch(url, options) {
+  const response = await fetch(url, withCsrfToken(options));
+  if(response.status !== 401) {
+    return response;
+  }
+
+  const refreshed = await refreshTokens();
+  if(!refreshed) {
+    return response;
+  }
+
+  // The refresh rotates the CSRF token, so it is read again for the retry
+  return fetch(url, withCsrfToken(options));
+}
@@ -1,4 +1,5 @@
 import React, { useState, useEffect, useContext, createContext } from "react";
+import apiFetch from "../apiFetch";
 
 const authContext = createContext();
 
@@ -43,10 +44,9 @@ function useProvideAuth() {
       });
   };
 
+  // Logging out changes state on the server, so it is a POST carrying the CSRF token
   const signout = () => {
-    return firebase
-      .auth()
-      .signOut()
+    return apiFetch('/logout', { method: 'POST' })
       .then(() => {
         setUser(false);
       });
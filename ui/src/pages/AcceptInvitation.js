@@ -0,0 +1,49 @@
+import { useEffect, useState } from 'react'
+import { Link, useLocation } from "react-router-dom";
+
+import FormErrors from '../components/FormErrors';
+import apiFetch from '../apiFetch';
+
+export default function AcceptInvitation() {
+  const location = useLocation();
+  const [organisation, setOrganisation] = useState(null);
+  const [loginRequired, setLoginRequired] = useState(false);
+  const [formErrors, setFormErrors] = useState([]);
+
+  useEffect(() => {
+    const token = new URLSearchParams(location.search).get('token');
+
+    apiFetch('/api/invitations/accept', {
+      method: 'POST',
+      body: JSON.stringify({ "Token": token })
+    }).then(response => {
+        if(response.status === 401) {
+          setLoginRequired(true);
+          return null;
+        }
+        return response.json();
+      })
+      .then(payload => {
+        if(!payload) {
+          return;
+        } else if(payload.Error) {
+          setFormErrors([payload.Error]);
+        } else {
+          setOrganisation(payload);
+        }
+      });
+  }, [location]);
+
+  return(
+    <div className="container">
+      <div className="row">
+        <div className="column column-50 column-offset-25">
+          <h1>Accept Invitation</h1>
+          <FormErrors errors={formErrors} />
+          {loginRequired && <p>Please <Link to='/login'>login</Link> or <Link to='/signup'>sign up</Link> with the invited e-mail address, then follow the link in your invitation again.</p>}
+          {organisation && <p>You have joined {organisation.Name}. <Link to='/'>View your vehicles</Link>.</p>}
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,49 @@
+import { useState } from 'react'
+
+import FormErrors from '../components/FormErrors';
+
+export default function ForgotPassword() {
+  const [email, setEmail] = useState("");
+  const [sent, setSent] = useState(false);
+  const [formErrors, setFormErrors] = useState([]);
+
+  function handleEmail(e) {
+    setEmail(e.target.value);
+  }
+
+  function submitForm() {
+    fetch('/password/forgot', {
+      method: 'POST',
+      body: JSON.stringify({ "Email": email })
+    }).then(response => response.json())
+      .then(payload => {
+        if(payload.Error) {
+          setFormErrors([payload.Error]);
+        } else {
+          setSent(true);
+        }
+      });
+  }
+
+  return(
+    <div className="container">
+      <div className="row">
+        <div className="column column-50 column-offset-25">
+          <h1>Forgotten Password</h1>
+          {sent ? (
+            <p>If that address is registered you will receive an e-mail with a link to reset your password.</p>
+          ) : (
+            <fieldset>
+              <FormErrors errors={formErrors} />
+
+              <label htmlFor="email">E-mail Address</label>
+              <input type="email" id="email" value={email} onChange={handleEmail} />
+
+              <button className="input-primary" onClick={submitForm}>Send Reset Link</button>
+            </fieldset>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
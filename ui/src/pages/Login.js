@@ -30,6 +30,11 @@ export default function Login() {
 function LoginForm({ onSuccess }) {
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
+  const [code, setCode] = useState("");
+  const [challengeToken, setChallengeToken] = useState(null);
+  // Single sign-on sends users with two factor authentication back here to finish logging in, with
+  // their challenge token kept in a cookie
+  const [twoFactorRequired, setTwoFactorRequired] = useState(new URLSearchParams(window.location.search).has('two_factor'));
   const [formErrors, setFormErrors] = useState([]);
 
   function handleFormInput(e) {
@@ -40,6 +45,9 @@ function LoginForm({ onSuccess }) {
       case 'password':
         setPassword(e.target.value);
         break;
+      case 'code':
+        setCode(e.target.value);
+        break;
     }
   }
 
@@ -50,6 +58,27 @@ function LoginForm({ onSuccess }) {
         "Email": email,
         "Password": password,
       })
+    }).then(response => response.json())
+      .then(payload => {
+        if(payload.Error) {
+          setFormErrors([payload.Error]);
+        } else if(payload.TwoFactorRequired) {
+          setFormErrors([]);
+          setChallengeToken(payload.ChallengeToken);
+          setTwoFactorRequired(true);
+        } else {
+          onSuccess();
+        }
+      });
+  }
+
+  function submitCode() {
+    fetch('/login/2fa', {
+      method: 'POST',
+      body: JSON.stringify({
+        "ChallengeToken": challengeToken,
+        "Code": code,
+      })
     }).then(response => response.json())
       .then(payload => {
         if(payload.Error) {
@@ -60,6 +89,19 @@ function LoginForm({ onSuccess }) {
       });
   }
 
+  if(twoFactorRequired) {
+    return(
+      <fieldset>
+        <FormErrors errors={formErrors} />
+
+        <label htmlFor="code">Authentication Code</label>
+        <input type="text" id="code" autoComplete="one-time-code" value={code} onChange={handleFormInput} />
+
+        <button className="input-primary" onClick={submitCode}>Verify</button>
+      </fieldset>
+    )
+  }
+
   return(
     <fieldset>
       <FormErrors errors={formErrors} />
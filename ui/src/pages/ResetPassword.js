@@ -0,0 +1,68 @@
+import { useState } from 'react'
+import { Link, useLocation } from "react-router-dom";
+
+import FormErrors from '../components/FormErrors';
+
+export default function ResetPassword() {
+  const location = useLocation();
+  const [password, setPassword] = useState("");
+  const [passwordConfirm, setPasswordConfirm] = useState("");
+  const [complete, setComplete] = useState(false);
+  const [formErrors, setFormErrors] = useState([]);
+
+  function handleFormInput(e) {
+    switch(e.target.id) {
+      case 'password':
+        setPassword(e.target.value);
+        break;
+      case 'passwordConfirm':
+        setPasswordConfirm(e.target.value);
+        break;
+    }
+  }
+
+  function submitForm() {
+    const token = new URLSearchParams(location.search).get('token');
+
+    fetch('/password/reset', {
+      method: 'POST',
+      body: JSON.stringify({
+        "Token": token,
+        "Password": password,
+        "PasswordConfirm": passwordConfirm,
+      })
+    }).then(response => response.json())
+      .then(payload => {
+        if(payload.Error) {
+          setFormErrors([].concat(payload.Error));
+        } else {
+          setComplete(true);
+        }
+      });
+  }
+
+  return(
+    <div className="container">
+      <div className="row">
+        <div className="column column-50 column-offset-25">
+          <h1>Reset Password</h1>
+          {complete ? (
+            <p>Your password has been changed. You may now <Link to='/login'>login</Link>.</p>
+          ) : (
+            <fieldset>
+              <FormErrors errors={formErrors} />
+
+              <label htmlFor="password">New Password</label>
+              <input type="password" id="password" value={password} onChange={handleFormInput} />
+
+              <label htmlFor="passwordConfirm">Confirm New Password</label>
+              <input type="password" id="passwordConfirm" value={passwordConfirm} onChange={handleFormInput} />
+
+              <button className="input-primary" onClick={submitForm}>Reset Password</button>
+            </fieldset>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -1,6 +1,7 @@
 import { useEffect, useState } from 'react';
 import { useParams, Redirect } from "react-router-dom";
 import Moment from 'react-moment';
+import apiFetch from '../apiFetch';
 
 export default function VehicleHistory() {
   const { registrationNumber } = useParams();
@@ -8,7 +9,7 @@ export default function VehicleHistory() {
   const [redirectBack, setRedirectBack] = useState(false);
 
   useEffect(()=> {
-    fetch(`/api/vehicles/${registrationNumber}`, { 'method' : 'GET' })
+    apiFetch(`/api/vehicles/${registrationNumber}`, { 'method' : 'GET' })
       .then(response => response.json())
       .then(vehicle => setVehicle(vehicle))
   }, [registrationNumber]);
@@ -26,7 +27,7 @@ export default function VehicleHistory() {
   }
 
   function handleDeleteVehicle(e) {
-    fetch(`/api/vehicles/${vehicle.RegistrationNumber}`, {
+    apiFetch(`/api/vehicles/${vehicle.RegistrationNumber}`, {
       method: 'DELETE',
     }).then(()=> setRedirectBack(true));
   }
@@ -3,17 +3,39 @@ import Moment from 'react-moment';
 import moment from 'moment';
 import { Link } from "react-router-dom";
 import FormErrors from '../components/FormErrors';
+import apiFetch from '../apiFetch';
 
 export default function VehicleList() {
   const [vehicles, setVehicles] = useState([]);
   const [searchFilter, setSearchFilter] = useState("");
+  const [plan, setPlan] = useState(null);
+  const [sort, setSort] = useState("registration");
+  const [nextPage, setNextPage] = useState(null);
 
   useEffect(()=> {
-    fetch('/api/vehicles', { method: 'GET' })
+    apiFetch('/api/account/plan', { method: 'GET' })
       .then(response => response.json())
-      .then(vehicles => setVehicles(vehicles || []));
+      .then(plan => setPlan(plan));
   }, []);
 
+  useEffect(()=> {
+    fetchVehicles('/api/vehicles?sort=' + sort, []);
+  }, [sort]);
+
+  function fetchVehicles(url, previousVehicles) {
+    apiFetch(url, { method: 'GET' })
+      .then(response => {
+        setNextPage(nextPageURL(response.headers.get('Link')));
+        return response.json();
+      })
+      .then(vehicles => setVehicles([...previousVehicles, ...(vehicles || [])]));
+  }
+
+  function handleLoadMore(e) {
+    e.preventDefault();
+    fetchVehicles(nextPage, vehicles);
+  }
+
   function handleOnVehicleAdded(addedVehicle) {
     setVehicles([...vehicles, addedVehicle]);
   }
@@ -51,19 +73,33 @@ export default function VehicleList() {
 
       <div className='row vehicle-list-content'>
         <div className='column'>
+          <select value={sort} onChange={e => setSort(e.target.value)}>
+            <option value='registration'>Sort by registration</option>
+            <option value='mot_due'>Sort by MOT due</option>
+            <option value='ved_due'>Sort by tax due</option>
+            <option value='manufacturer'>Sort by make</option>
+            <option value='created'>Sort by date added</option>
+          </select>
           <VehicleTable vehicles={filteredVehicles()}/>
+          {nextPage && <a href='#' className='button' onClick={handleLoadMore}>Load more</a>}
         </div>
 
         <div className='column add-vehicle'>
           <h4>Add a new vehicle</h4>
           <AddVehicleForm onVehicleAdded={handleOnVehicleAdded} />
-          <p>You may monitor up to five vehicles.</p>
+          {plan && <p>Your {plan.Name} plan lets you monitor up to {plan.VehicleLimit} vehicles.</p>}
         </div>
       </div>
     </div>
   )
 }
 
+// nextPageURL picks the next page out of a Link header, if there is one
+function nextPageURL(linkHeader) {
+  const match = /<([^>]+)>;\s*rel="next"/.exec(linkHeader || '');
+  return match ? match[1] : null;
+}
+
 function SearchVehicleForm({ onSearchUpdate }) {
   const [searchQuery, setSearchQuery] = useState("")
 
@@ -90,7 +126,7 @@ function AddVehicleForm({ onVehicleAdded }) {
   }
 
   function submitForm(e) {
-    fetch('/api/vehicles', {
+    apiFetch('/api/vehicles', {
       method: 'POST',
       body: JSON.stringify({ "RegistrationNumber" : registrationNumber })
     }).then(response => response.json())
@@ -148,7 +184,7 @@ function VehicleTable({ vehicles }) {
   )
 }
 
-function Vehicle({ ID, RegistrationNumber, Manufacturer, Model, MotDue, VEDDue, MOTHistory }) {
+function Vehicle({ ID, RegistrationNumber, Manufacturer, Model, MotDue, VEDDue, MOTHistory, Access }) {
   function expiredOrDue(timestamp) {
     if(moment(timestamp).isBefore(moment())){
       return 'Expired';
@@ -193,6 +229,7 @@ function Vehicle({ ID, RegistrationNumber, Manufacturer, Model, MotDue, VEDDue,
     <tr>
       <td>
         <Link to={"/" + RegistrationNumber}>{RegistrationNumber}</Link>
+        {Access && Access !== 'owner' && <small> (shared)</small>}
       </td>
       <td>{Manufacturer} {Model}</td>
       <td>{expiredOrDue(MotDue)} <Moment format='DD/MM/YYYY'>{MotDue}</Moment></td>
@@ -0,0 +1,38 @@
+import { useEffect, useState } from 'react'
+import { Link, useLocation } from "react-router-dom";
+
+import FormErrors from '../components/FormErrors';
+
+export default function VerifyEmail() {
+  const location = useLocation();
+  const [verified, setVerified] = useState(false);
+  const [formErrors, setFormErrors] = useState([]);
+
+  useEffect(() => {
+    const token = new URLSearchParams(location.search).get('token');
+
+    fetch('/verify-email', {
+      method: 'POST',
+      body: JSON.stringify({ "Token": token })
+    }).then(response => response.json())
+      .then(payload => {
+        if(payload.Error) {
+          setFormErrors([payload.Error]);
+        } else {
+          setVerified(true);
+        }
+      });
+  }, [location]);
+
+  return(
+    <div className="container">
+      <div className="row">
+        <div className="column column-50 column-offset-25">
+          <h1>Verify E-mail</h1>
+          <FormErrors errors={formErrors} />
+          {verified && <p>Your e-mail address has been verified. You may now <Link to='/login'>login</Link>.</p>}
+        </div>
+      </div>
+    </div>
+  );
+}